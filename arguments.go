@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// arguments represents the argument list of a process. It can be configured
+// either as a JSON array of argv entries or as a single string that is split
+// following POSIX shell-word rules.
+type arguments struct {
+	raw    string
+	values []string
+	isList bool
+}
+
+func (a *arguments) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var values []string
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		a.raw = ""
+		a.values = values
+		a.isList = true
+		return nil
+	}
+
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	values, err := splitShellWords(raw)
+	if err != nil {
+		return fmt.Errorf("invalid arguments '%s': %v", raw, err)
+	}
+	a.raw = raw
+	a.values = values
+	a.isList = false
+	return nil
+}
+
+func (a arguments) MarshalJSON() ([]byte, error) {
+	if a.isList {
+		return json.Marshal(a.values)
+	}
+	return json.Marshal(a.raw)
+}
+
+// IsEmpty returns true if there are no arguments
+func (a *arguments) IsEmpty() bool {
+	return a == nil || (len(a.values) == 0 && a.raw == "")
+}
+
+// Values returns the argv entries
+func (a *arguments) Values() []string {
+	if a == nil {
+		return nil
+	}
+	return a.values
+}
+
+// String returns the arguments as a command line string
+func (a *arguments) String() string {
+	if a == nil {
+		return ""
+	}
+	if !a.isList {
+		return a.raw
+	}
+	var quoted []string
+	for _, v := range a.values {
+		quoted = append(quoted, quoteShellWord(v))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Replace returns a copy of the arguments with the replacer function applied to each value
+func (a *arguments) Replace(replacer func(string) string) *arguments {
+	if a == nil {
+		return nil
+	}
+	values := make([]string, len(a.values))
+	for i, v := range a.values {
+		values[i] = replacer(v)
+	}
+	return &arguments{
+		raw:    replacer(a.raw),
+		values: values,
+		isList: a.isList,
+	}
+}
+
+// Append returns a copy of the arguments with the other arguments added at the end
+func (a *arguments) Append(other *arguments) *arguments {
+	if a.IsEmpty() {
+		return other
+	}
+	if other.IsEmpty() {
+		return a
+	}
+	values := append(append([]string{}, a.values...), other.values...)
+	if !a.isList && !other.isList {
+		return &arguments{
+			raw:    fmt.Sprintf("%s %s", a.raw, other.raw),
+			values: values,
+		}
+	}
+	return &arguments{
+		values: values,
+		isList: true,
+	}
+}
+
+// newCommand creates the exec.Cmd for a process name and arguments, if shell is enabled
+// the command line is executed through the system shell.
+func newCommand(ctx context.Context, name string, args *arguments, shell bool) *exec.Cmd {
+	if shell {
+		return newShellCommand(ctx, name, args)
+	}
+	return exec.CommandContext(ctx, name, args.Values()...)
+}
+
+// shellCommandLine returns the command line for the system shell, the argument lists are quoted
+// with the quote function of the shell while the argument strings are used as they are
+func shellCommandLine(name string, args *arguments, quote func(string) string) string {
+	if args.IsEmpty() {
+		return name
+	}
+	if !args.isList {
+		return fmt.Sprintf("%s %s", name, args.raw)
+	}
+	var quoted []string
+	for _, v := range args.values {
+		quoted = append(quoted, quote(v))
+	}
+	return fmt.Sprintf("%s %s", name, strings.Join(quoted, " "))
+}
+
+// splitShellWords splits a string into words following the POSIX shell quoting rules
+func splitShellWords(value string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inWord := false
+	inSingleQuote := false
+	inDoubleQuote := false
+	escaped := false
+
+	for _, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+			if r == '\n' {
+				// line continuation
+				continue
+			}
+			if inDoubleQuote && r != '$' && r != '`' && r != '"' && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			inWord = true
+		case inSingleQuote:
+			if r == '\'' {
+				inSingleQuote = false
+			} else {
+				current.WriteRune(r)
+			}
+		case inDoubleQuote:
+			if r == '"' {
+				inDoubleQuote = false
+			} else if r == '\\' {
+				escaped = true
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			// the word starts with the escaped rune, a line continuation doesn't start a word
+			escaped = true
+		case r == '\'':
+			inSingleQuote = true
+			inWord = true
+		case r == '"':
+			inDoubleQuote = true
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if escaped && !inDoubleQuote {
+		return nil, errors.New("unexpected end of string after escape character")
+	}
+	if inSingleQuote || inDoubleQuote || escaped {
+		return nil, errors.New("unterminated quoted string")
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// quoteShellWord quotes a value so it's interpreted as a single word by a POSIX shell
+func quoteShellWord(value string) string {
+	if value == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(value, " \t\n\r'\"\\$`|&;<>()*?[]{}~#!") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// quoteWindowsWord quotes a value so it's interpreted as a single argument by cmd.exe and the
+// Microsoft C runtime, backslashes are only escaped when they precede a double quote
+func quoteWindowsWord(value string) string {
+	if value == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(value, " \t\n\r\"&|<>^(),;=") {
+		return value
+	}
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	backslashes := 0
+	for _, r := range value {
+		switch r {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			quoted.WriteString(strings.Repeat(`\`, backslashes*2+1))
+		default:
+			quoted.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		quoted.WriteRune(r)
+	}
+	quoted.WriteString(strings.Repeat(`\`, backslashes*2))
+	quoted.WriteByte('"')
+	return quoted.String()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+		err      bool
+	}{
+		{value: "", expected: nil},
+		{value: "  a  b\tc\n", expected: []string{"a", "b", "c"}},
+		{value: `--name 'hello world'`, expected: []string{"--name", "hello world"}},
+		{value: `"a b" c`, expected: []string{"a b", "c"}},
+		{value: `a\ b`, expected: []string{"a b"}},
+		{value: `'it'\''s'`, expected: []string{"it's"}},
+		{value: `"say \"hi\""`, expected: []string{`say "hi"`}},
+		{value: `"keep \n escape"`, expected: []string{`keep \n escape`}},
+		{value: `'no \escape'`, expected: []string{`no \escape`}},
+		{value: `''`, expected: []string{""}},
+		{value: `a""b`, expected: []string{"ab"}},
+		{value: "a\\\nb", expected: []string{"ab"}},
+		{value: "a \\\n b", expected: []string{"a", "b"}},
+		{value: "a \\\n", expected: []string{"a"}},
+		{value: "\\\na", expected: []string{"a"}},
+		{value: `a \  b`, expected: []string{"a", " ", "b"}},
+		{value: `'unterminated`, err: true},
+		{value: `"unterminated`, err: true},
+		{value: `trailing\`, err: true},
+	}
+	for _, test := range tests {
+		actual, err := splitShellWords(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("splitShellWords(%q) error = %v", test.value, err)
+			continue
+		}
+		if !test.err && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("splitShellWords(%q) = %q, expected %q", test.value, actual, test.expected)
+		}
+	}
+}
+
+func TestQuoteShellWord(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "", expected: "''"},
+		{value: "simple", expected: "simple"},
+		{value: "--flag=value", expected: "--flag=value"},
+		{value: "a b", expected: "'a b'"},
+		{value: "it's", expected: `'it'\''s'`},
+		{value: "$HOME", expected: "'$HOME'"},
+	}
+	for _, test := range tests {
+		actual := quoteShellWord(test.value)
+		if actual != test.expected {
+			t.Errorf("quoteShellWord(%q) = %q, expected %q", test.value, actual, test.expected)
+		}
+		// the quoted value is parsed back as the same single word
+		words, err := splitShellWords(actual)
+		if err != nil || len(words) != 1 || words[0] != test.value {
+			t.Errorf("splitShellWords(quoteShellWord(%q)) = %q, %v", test.value, words, err)
+		}
+	}
+}
+
+func TestQuoteWindowsWord(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "", expected: `""`},
+		{value: "simple", expected: "simple"},
+		{value: `C:\path\file.txt`, expected: `C:\path\file.txt`},
+		{value: "a b", expected: `"a b"`},
+		{value: "it's", expected: "it's"},
+		{value: `say "hi"`, expected: `"say \"hi\""`},
+		{value: `C:\my dir\`, expected: `"C:\my dir\\"`},
+		{value: `a\"b`, expected: `"a\\\"b"`},
+		{value: "a&b", expected: `"a&b"`},
+	}
+	for _, test := range tests {
+		if actual := quoteWindowsWord(test.value); actual != test.expected {
+			t.Errorf("quoteWindowsWord(%q) = %s, expected %s", test.value, actual, test.expected)
+		}
+	}
+}
+
+func TestShellCommandLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		quote    func(string) string
+		expected string
+	}{
+		{name: "empty", json: `""`, quote: quoteShellWord, expected: "cmd"},
+		{name: "string", json: `"--a 'b c'"`, quote: quoteShellWord, expected: "cmd --a 'b c'"},
+		{name: "list posix", json: `["--a", "b c"]`, quote: quoteShellWord, expected: "cmd --a 'b c'"},
+		{name: "list windows", json: `["--a", "b c"]`, quote: quoteWindowsWord, expected: `cmd --a "b c"`},
+	}
+	for _, test := range tests {
+		var args arguments
+		if err := json.Unmarshal([]byte(test.json), &args); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if actual := shellCommandLine("cmd", &args, test.quote); actual != test.expected {
+			t.Errorf("%s: shellCommandLine = %s, expected %s", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestArgumentsJSON(t *testing.T) {
+	tests := []struct {
+		json     string
+		values   []string
+		expected string
+	}{
+		{json: `"a 'b c'"`, values: []string{"a", "b c"}, expected: `"a 'b c'"`},
+		{json: `["a", "b c"]`, values: []string{"a", "b c"}, expected: `["a","b c"]`},
+	}
+	for _, test := range tests {
+		var args arguments
+		if err := json.Unmarshal([]byte(test.json), &args); err != nil {
+			t.Fatalf("unmarshal %s: %v", test.json, err)
+		}
+		if !reflect.DeepEqual(args.Values(), test.values) {
+			t.Errorf("unmarshal %s: values = %q, expected %q", test.json, args.Values(), test.values)
+		}
+		data, err := json.Marshal(args)
+		if err != nil || string(data) != test.expected {
+			t.Errorf("marshal %s = %s, %v, expected %s", test.json, data, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// artifacts configures where the output of each iteration is persisted
+type artifacts struct {
+	Path         string `json:"path"`
+	MaxSize      int    `json:"maxSize"`
+	OnlyFailures bool   `json:"onlyFailures"`
+}
+
+var invalidFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// write persists the stdout and stderr of an iteration attempt, and sets the artifact paths in the data point,
+// the retried attempts of an iteration include the attempt number in the file name
+func (a *artifacts) write(scenarioName string, phase string, index int, attempt int, dataPoint *scenarioDataPoint) error {
+	if a == nil || a.Path == "" {
+		return nil
+	}
+	if a.OnlyFailures && dataPoint.Status == dataPointStatusSuccess {
+		return nil
+	}
+
+	folder := a.folder(scenarioName)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+
+	prefix := filepath.Join(folder, fmt.Sprintf("%s-%04d", phase, index))
+	if attempt > 0 {
+		prefix = fmt.Sprintf("%s-attempt%d", prefix, attempt)
+	}
+	stdoutPath := prefix + ".stdout.txt"
+	if err := os.WriteFile(stdoutPath, a.truncate(dataPoint.stdout), 0644); err != nil {
+		return err
+	}
+	stderrPath := prefix + ".stderr.txt"
+	if err := os.WriteFile(stderrPath, a.truncate(dataPoint.stderr), 0644); err != nil {
+		return err
+	}
+
+	dataPoint.StdoutPath = stdoutPath
+	dataPoint.StderrPath = stderrPath
+	return nil
+}
+
+// folder returns the artifacts folder of a scenario
+func (a *artifacts) folder(scenarioName string) string {
+	return filepath.Join(a.Path, invalidFileNameChars.ReplaceAllString(scenarioName, "_"))
+}
+
+// validateArtifactsFolders returns an error if two scenarios persist their artifacts in the same folder
+func validateArtifactsFolders(cfg *config) error {
+	scenariosByFolder := map[string]string{}
+	for _, sce := range cfg.Scenarios {
+		sceArtifacts := sce.Artifacts
+		if sceArtifacts == nil {
+			sceArtifacts = cfg.Artifacts
+		}
+		if sceArtifacts == nil || sceArtifacts.Path == "" {
+			continue
+		}
+		folder := sceArtifacts.folder(sce.Name)
+		if other, ok := scenariosByFolder[folder]; ok {
+			return fmt.Errorf("scenarios '%s' and '%s' use the same artifacts folder '%s'", other, sce.Name, folder)
+		}
+		scenariosByFolder[folder] = sce.Name
+	}
+	return nil
+}
+
+// truncate limits the output to the configured max size
+func (a *artifacts) truncate(output []byte) []byte {
+	if a.MaxSize > 0 && len(output) > a.MaxSize {
+		return output[:a.MaxSize]
+	}
+	return output
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type (
+	// exitCodes is a set of exit codes, it can be configured as a single number or as an array
+	exitCodes []int
+
+	// outputAssertion contains the regular expressions the output of a process must (or must not) match
+	outputAssertion struct {
+		MustMatch    []string `json:"mustMatch"`
+		MustNotMatch []string `json:"mustNotMatch"`
+		mustMatch    []*regexp.Regexp
+		mustNotMatch []*regexp.Regexp
+	}
+)
+
+func (e *exitCodes) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var values []int
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		*e = values
+		return nil
+	}
+
+	var value int
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*e = exitCodes{value}
+	return nil
+}
+
+func (e exitCodes) contains(exitCode int) bool {
+	for _, value := range e {
+		if value == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// compile compiles the regular expressions of the assertion
+func (o *outputAssertion) compile() error {
+	if o == nil {
+		return nil
+	}
+	o.mustMatch = nil
+	o.mustNotMatch = nil
+	for _, expr := range o.MustMatch {
+		rgx, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid mustMatch regex '%s': %v", expr, err)
+		}
+		o.mustMatch = append(o.mustMatch, rgx)
+	}
+	for _, expr := range o.MustNotMatch {
+		rgx, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid mustNotMatch regex '%s': %v", expr, err)
+		}
+		o.mustNotMatch = append(o.mustNotMatch, rgx)
+	}
+	return nil
+}
+
+// check returns the list of failed assertions for an output
+func (o *outputAssertion) check(name string, output []byte) []string {
+	if o == nil {
+		return nil
+	}
+	var failures []string
+	for _, rgx := range o.mustMatch {
+		if !rgx.Match(output) {
+			failures = append(failures, fmt.Sprintf("%s doesn't match '%s'", name, rgx.String()))
+		}
+	}
+	for _, rgx := range o.mustNotMatch {
+		if rgx.Match(output) {
+			failures = append(failures, fmt.Sprintf("%s matches '%s'", name, rgx.String()))
+		}
+	}
+	return failures
+}
+
+// checkAssertions validates the exit code and the output of a process against the scenario assertions
+func checkAssertions(sce *scenario, exitCode int, checkExitCode bool, stdout []byte, stderr []byte) error {
+	var failures []string
+
+	expectedExitCodes := exitCodes{0}
+	if len(sce.ExpectedExitCode) > 0 {
+		expectedExitCodes = sce.ExpectedExitCode
+	}
+	if checkExitCode && !expectedExitCodes.contains(exitCode) {
+		failures = append(failures, fmt.Sprintf("unexpected exit code %d (expected: %v)", exitCode, []int(expectedExitCodes)))
+	}
+
+	failures = append(failures, sce.Stdout.check("stdout", stdout)...)
+	failures = append(failures, sce.Stderr.check("stderr", stderr)...)
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+	return nil
+}
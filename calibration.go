@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+const defaultCalibrationCount = 30
+
+type (
+	// calibration configures the measurement of the harness overhead using a no-op process
+	calibration struct {
+		ProcessName      *string    `json:"processName"`
+		ProcessArguments *arguments `json:"processArguments"`
+		Count            int        `json:"count"`
+		Subtract         bool       `json:"subtract"`
+	}
+
+	// calibrationResult is the baseline duration measured for a scenario
+	calibrationResult struct {
+		ProcessName string  `json:"processName"`
+		Count       int     `json:"count"`
+		Mean        float64 `json:"mean"`
+		Stdev       float64 `json:"stdev"`
+		StdErr      float64 `json:"stderr"`
+		Subtracted  bool    `json:"subtracted"`
+	}
+)
+
+func (c *calibration) validate() error {
+	if c.ProcessName == nil || *c.ProcessName == "" {
+		processName := "/bin/true"
+		if runtime.GOOS == "windows" {
+			processName = "cmd"
+			c.ProcessArguments = &arguments{values: []string{"/C", "exit", "0"}, isList: true}
+		}
+		c.ProcessName = &processName
+	}
+	if c.Count == 0 {
+		c.Count = defaultCalibrationCount
+	} else if c.Count < 2 {
+		return errors.New("calibration count must be greater or equal than 2")
+	}
+	return nil
+}
+
+// getCalibrationScenario returns a copy of the scenario running the no-op process with the same settings
+// (working directory, environment variables, shell, stdin and scheduling) but without any assertion or hook
+func getCalibrationScenario(sce *scenario, cal *calibration) scenario {
+	nullScenario := *sce
+	nullScenario.ProcessName = cal.ProcessName
+	nullScenario.ProcessArguments = cal.ProcessArguments
+	nullScenario.MetricsFilePath = nil
+	nullScenario.Setup = nil
+	nullScenario.Teardown = nil
+	nullScenario.BeforeEach = nil
+	nullScenario.AfterEach = nil
+	nullScenario.ExpectedExitCode = nil
+	nullScenario.Stdout = nil
+	nullScenario.Stderr = nil
+	nullScenario.Artifacts = nil
+	nullScenario.FailFast = nil
+	nullScenario.MaxFailures = nil
+	nullScenario.MaxFailureRatio = nil
+	nullScenario.Retries = 0
+	nullScenario.Readiness = nil
+	nullScenario.Service = nil
+	nullScenario.HTTPProbe = nil
+	nullScenario.Sampling = nil
+	return nullScenario
+}
+
+// calibrateScenario measures the baseline duration of the no-op process using the scenario settings,
+// it returns nil if the calibration is not enabled or the scenario doesn't run a process
+func calibrateScenario(sce *scenario, cfg *config, label string) (*calibrationResult, error) {
+	if cfg.Calibration == nil || sce.HTTPProbe != nil {
+		return nil, nil
+	}
+
+	nullScenario := getCalibrationScenario(sce, cfg.Calibration)
+	run := newFixedRun(&nullScenario, cfg.Calibration.Count, 0)
+	runScenario(run, label)
+	for _, item := range run.data {
+		if item.Error != nil {
+			return nil, errors.New(fmt.Sprintf("calibration process '%s' failed:%s", *cfg.Calibration.ProcessName, item.Error.Error()))
+		}
+	}
+
+	durations := run.successfulDurations()
+	if len(durations) == 0 {
+		return nil, errors.New("calibration was stopped before running any iteration")
+	}
+	mean, _ := stats.Mean(durations)
+	stdev, _ := stats.StandardDeviationSample(durations)
+	return &calibrationResult{
+		ProcessName: *cfg.Calibration.ProcessName,
+		Count:       len(durations),
+		Mean:        mean,
+		Stdev:       stdev,
+		StdErr:      stdev / math.Sqrt(float64(len(durations))),
+		Subtracted:  cfg.Calibration.Subtract,
+	}, nil
+}
+
+// String returns the baseline with its standard error
+func (c *calibrationResult) String() string {
+	return fmt.Sprintf("%v ± %v", time.Duration(c.Mean), time.Duration(c.StdErr))
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/montanaflynn/stats"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	comparisonFaster       = "faster"
+	comparisonSlower       = "slower"
+	comparisonInconclusive = "inconclusive"
+)
+
+type (
+	// comparisonDifference is the difference of an estimate against the baseline
+	comparisonDifference struct {
+		Difference         float64            `json:"difference"`
+		DifferenceInterval confidenceInterval `json:"differenceInterval"`
+		Relative           float64            `json:"relative"`
+		RelativeInterval   confidenceInterval `json:"relativeInterval"`
+	}
+
+	// comparisonResult is the comparison of a scenario against the baseline scenario
+	comparisonResult struct {
+		Baseline          string               `json:"baseline"`
+		Mean              comparisonDifference `json:"mean"`
+		Median            comparisonDifference `json:"median"`
+		WelchT            float64              `json:"welchT"`
+		WelchDF           float64              `json:"welchDF"`
+		WelchPValue       float64              `json:"welchPValue"`
+		MannWhitneyU      float64              `json:"mannWhitneyU"`
+		MannWhitneyPValue float64              `json:"mannWhitneyPValue"`
+		Result            string               `json:"result"`
+	}
+)
+
+// compareWithBaseline compares the durations of each scenario against the baseline scenario
+func compareWithBaseline(resScenario []scenarioResult, cfg *config) {
+	if cfg.Baseline == "" {
+		return
+	}
+	var baseline *scenarioResult
+	for idx := range resScenario {
+		if resScenario[idx].Name == cfg.Baseline {
+			baseline = &resScenario[idx]
+			break
+		}
+	}
+	if baseline == nil || len(baseline.DataFloat) < 2 {
+		return
+	}
+	for idx := range resScenario {
+		if &resScenario[idx] == baseline || len(resScenario[idx].DataFloat) < 2 {
+			continue
+		}
+		resScenario[idx].Comparison = compareDurations(baseline.Name, baseline.DataFloat, resScenario[idx].DataFloat, cfg.ConfidenceIntervals)
+	}
+}
+
+// compareDurations compares the durations of a scenario against the baseline durations
+func compareDurations(baselineName string, baseline []float64, values []float64, ci *confidenceIntervals) *comparisonResult {
+	baselineMean, _ := stats.Mean(baseline)
+	baselineMedian, _ := stats.Median(baseline)
+	mean, _ := stats.Mean(values)
+	median, _ := stats.Median(values)
+
+	// bootstrap of the differences, each sample is resampled independently
+	rnd := rand.New(rand.NewSource(*ci.Seed))
+	meanDiffs := make([]float64, ci.Resamples)
+	meanRelatives := make([]float64, ci.Resamples)
+	medianDiffs := make([]float64, ci.Resamples)
+	medianRelatives := make([]float64, ci.Resamples)
+	baselineSample := make([]float64, len(baseline))
+	sample := make([]float64, len(values))
+	for i := 0; i < ci.Resamples; i++ {
+		for j := range baselineSample {
+			baselineSample[j] = baseline[rnd.Intn(len(baseline))]
+		}
+		for j := range sample {
+			sample[j] = values[rnd.Intn(len(values))]
+		}
+		sort.Float64s(baselineSample)
+		sort.Float64s(sample)
+		sBaselineMean, _ := stats.Mean(baselineSample)
+		sBaselineMedian := sortedMedian(baselineSample)
+		sMean, _ := stats.Mean(sample)
+		sMedian := sortedMedian(sample)
+		meanDiffs[i] = sMean - sBaselineMean
+		meanRelatives[i] = relativeDifference(sMean, sBaselineMean)
+		medianDiffs[i] = sMedian - sBaselineMedian
+		medianRelatives[i] = relativeDifference(sMedian, sBaselineMedian)
+	}
+
+	result := &comparisonResult{
+		Baseline: baselineName,
+		Mean: comparisonDifference{
+			Difference:         mean - baselineMean,
+			DifferenceInterval: getBootstrapInterval(meanDiffs, ci.Level),
+			Relative:           relativeDifference(mean, baselineMean),
+			RelativeInterval:   getBootstrapInterval(meanRelatives, ci.Level),
+		},
+		Median: comparisonDifference{
+			Difference:         median - baselineMedian,
+			DifferenceInterval: getBootstrapInterval(medianDiffs, ci.Level),
+			Relative:           relativeDifference(median, baselineMedian),
+			RelativeInterval:   getBootstrapInterval(medianRelatives, ci.Level),
+		},
+	}
+	result.WelchT, result.WelchDF, result.WelchPValue = welchTTest(baseline, values)
+	result.MannWhitneyU, result.MannWhitneyPValue = mannWhitneyUTest(baseline, values)
+
+	// the difference is only reported when both tests are significant and the mean and the median agree
+	alpha := 1 - ci.Level
+	result.Result = comparisonInconclusive
+	if result.WelchPValue < alpha && result.MannWhitneyPValue < alpha {
+		if result.Mean.Difference < 0 && result.Median.Difference < 0 {
+			result.Result = comparisonFaster
+		} else if result.Mean.Difference > 0 && result.Median.Difference > 0 {
+			result.Result = comparisonSlower
+		}
+	}
+	return result
+}
+
+// relativeDifference returns the difference of the value relative to the baseline value
+func relativeDifference(value float64, baseline float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return (value - baseline) / baseline
+}
+
+// welchTTest returns the t statistic, the degrees of freedom and the two-sided p-value of Welch's t-test
+func welchTTest(a []float64, b []float64) (float64, float64, float64) {
+	na, nb := float64(len(a)), float64(len(b))
+	meanA, _ := stats.Mean(a)
+	meanB, _ := stats.Mean(b)
+	varA, _ := stats.SampleVariance(a)
+	varB, _ := stats.SampleVariance(b)
+	seA, seB := varA/na, varB/nb
+	if seA+seB == 0 {
+		if meanA == meanB {
+			return 0, na + nb - 2, 1
+		}
+		// the difference is certain, the largest finite statistic keeps the sign and can be serialized
+		return math.Copysign(math.MaxFloat64, meanB-meanA), na + nb - 2, 0
+	}
+	t := (meanB - meanA) / math.Sqrt(seA+seB)
+	df := (seA + seB) * (seA + seB) / (seA*seA/(na-1) + seB*seB/(nb-1))
+	return t, df, 2 * (1 - studentTCDF(math.Abs(t), df))
+}
+
+// mannWhitneyUTest returns the U statistic of the second sample and the two-sided p-value of the
+// Mann-Whitney U test, using the normal approximation with tie and continuity corrections
+func mannWhitneyUTest(a []float64, b []float64) (float64, float64) {
+	type rankedValue struct {
+		value   float64
+		isFromB bool
+	}
+	na, nb := float64(len(a)), float64(len(b))
+	values := make([]rankedValue, 0, len(a)+len(b))
+	for _, v := range a {
+		values = append(values, rankedValue{value: v})
+	}
+	for _, v := range b {
+		values = append(values, rankedValue{value: v, isFromB: true})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].value < values[j].value
+	})
+
+	// average ranks for the ties
+	var rankSumB, tiesCorrection float64
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].value == values[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if values[k].isFromB {
+				rankSumB += rank
+			}
+		}
+		ties := float64(j - i)
+		tiesCorrection += ties*ties*ties - ties
+		i = j
+	}
+
+	u := rankSumB - nb*(nb+1)/2
+	n := na + nb
+	meanU := na * nb / 2
+	sigmaU := math.Sqrt(na * nb / 12 * ((n + 1) - tiesCorrection/(n*(n-1))))
+	if sigmaU == 0 {
+		return u, 1
+	}
+	z := (math.Abs(u-meanU) - 0.5) / sigmaU
+	if z < 0 {
+		z = 0
+	}
+	return u, math.Erfc(z / math.Sqrt2)
+}
+
+func printComparisonTable(resScenario []scenarioResult, cfg *config) {
+	if cfg.Baseline == "" {
+		return
+	}
+	fmt.Printf("### Comparison with baseline: %v (%v%% CI)\n\n", cfg.Baseline, cfg.ConfidenceIntervals.Level*100)
+	comparisonTable := tablewriter.NewWriter(os.Stdout)
+	comparisonTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	comparisonTable.SetCenterSeparator("|")
+	comparisonTable.SetAutoWrapText(false)
+	comparisonTable.SetHeader([]string{"Name", "Mean diff", "Mean diff %", "Median diff", "Median diff %", "Welch p-value", "Mann-Whitney p-value", "Result"})
+	for scidx := 0; scidx < len(resScenario); scidx++ {
+		comparison := resScenario[scidx].Comparison
+		if comparison == nil {
+			continue
+		}
+		comparisonTable.Append([]string{
+			resScenario[scidx].Name,
+			fmt.Sprintf("%v %v", time.Duration(comparison.Mean.Difference), comparison.Mean.DifferenceInterval),
+			formatRelativeDifference(comparison.Mean),
+			fmt.Sprintf("%v %v", time.Duration(comparison.Median.Difference), comparison.Median.DifferenceInterval),
+			formatRelativeDifference(comparison.Median),
+			fmt.Sprint(toFixed(comparison.WelchPValue, 6)),
+			fmt.Sprint(toFixed(comparison.MannWhitneyPValue, 6)),
+			comparison.Result,
+		})
+	}
+	comparisonTable.Render()
+	fmt.Println()
+}
+
+// formatRelativeDifference returns the relative difference and its interval as percentages
+func formatRelativeDifference(diff comparisonDifference) string {
+	return fmt.Sprintf("%+.2f%% [%+.2f%%, %+.2f%%]", diff.Relative*100, diff.RelativeInterval.Lower*100, diff.RelativeInterval.Upper*100)
+}
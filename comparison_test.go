@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func sequence(from int, to int) []float64 {
+	var values []float64
+	for i := from; i <= to; i++ {
+		values = append(values, float64(i))
+	}
+	return values
+}
+
+func TestWelchTTest(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         []float64
+		b         []float64
+		expectedT float64
+		expectedD float64
+		expectedP float64
+	}{
+		// equal variances and sizes: t = 5 / sqrt(2 * 9.1667 / 10), df = 18
+		{name: "shifted", a: sequence(1, 10), b: sequence(6, 15), expectedT: 3.692745, expectedD: 18, expectedP: 0.001665},
+		{name: "reversed", a: sequence(6, 15), b: sequence(1, 10), expectedT: -3.692745, expectedD: 18, expectedP: 0.001665},
+		{name: "same", a: sequence(1, 10), b: sequence(1, 10), expectedT: 0, expectedD: 18, expectedP: 1},
+		// unequal variances: se = 9.1667/10 + 0, df = n - 1 of the sample with variance
+		{name: "constant sample", a: sequence(1, 10), b: []float64{5.5, 5.5, 5.5}, expectedT: 0, expectedD: 9, expectedP: 1},
+		{name: "zero variance", a: []float64{1, 1, 1}, b: []float64{2, 2, 2}, expectedT: math.MaxFloat64, expectedD: 4, expectedP: 0},
+		{name: "zero variance equal", a: []float64{1, 1, 1}, b: []float64{1, 1, 1}, expectedT: 0, expectedD: 4, expectedP: 1},
+	}
+	for _, test := range tests {
+		tValue, df, p := welchTTest(test.a, test.b)
+		if math.Abs(tValue-test.expectedT) > 1e-6 && tValue != test.expectedT {
+			t.Errorf("%s: t = %v, expected %v", test.name, tValue, test.expectedT)
+		}
+		if math.Abs(df-test.expectedD) > 1e-6 {
+			t.Errorf("%s: df = %v, expected %v", test.name, df, test.expectedD)
+		}
+		if math.Abs(p-test.expectedP) > 1e-6 {
+			t.Errorf("%s: p = %v, expected %v", test.name, p, test.expectedP)
+		}
+	}
+}
+
+func TestMannWhitneyUTest(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         []float64
+		b         []float64
+		expectedU float64
+		expectedP float64
+	}{
+		// 5 tied values, normal approximation with tie and continuity corrections
+		{name: "shifted with ties", a: sequence(1, 10), b: sequence(6, 15), expectedU: 87.5, expectedP: 0.005075},
+		{name: "reversed", a: sequence(6, 15), b: sequence(1, 10), expectedU: 12.5, expectedP: 0.005075},
+		{name: "same", a: sequence(1, 10), b: sequence(1, 10), expectedU: 50, expectedP: 1},
+		{name: "all tied", a: []float64{1, 1, 1}, b: []float64{1, 1, 1}, expectedU: 4.5, expectedP: 1},
+	}
+	for _, test := range tests {
+		u, p := mannWhitneyUTest(test.a, test.b)
+		if u != test.expectedU {
+			t.Errorf("%s: U = %v, expected %v", test.name, u, test.expectedU)
+		}
+		if math.Abs(p-test.expectedP) > 1e-6 {
+			t.Errorf("%s: p = %v, expected %v", test.name, p, test.expectedP)
+		}
+	}
+}
+
+func TestCompareDurations(t *testing.T) {
+	seed := int64(1)
+	ci := &confidenceIntervals{Level: 0.95, Resamples: 1000, Seed: &seed}
+	tests := []struct {
+		name     string
+		values   []float64
+		expected string
+	}{
+		{name: "slower", values: sequence(16, 45), expected: comparisonSlower},
+		{name: "faster", values: sequence(-14, 15), expected: comparisonFaster},
+		{name: "same", values: sequence(1, 30), expected: comparisonInconclusive},
+		{name: "constant", values: []float64{20, 20, 20}, expected: comparisonInconclusive},
+	}
+	for _, test := range tests {
+		result := compareDurations("baseline", sequence(1, 30), test.values, ci)
+		if result.Result != test.expected {
+			t.Errorf("%s: result = %v, expected %v", test.name, result.Result, test.expected)
+		}
+		// the results must always be exportable
+		if _, err := json.Marshal(result); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+
+	result := compareDurations("baseline", []float64{1, 1, 1}, []float64{2, 2, 2}, ci)
+	if result.Result != comparisonSlower || result.Mean.Relative != 1 {
+		t.Errorf("zero variance: result = %v, relative = %v", result.Result, result.Mean.Relative)
+	}
+	if _, err := json.Marshal(result); err != nil {
+		t.Errorf("zero variance: %v", err)
+	}
+}
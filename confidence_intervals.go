@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+const (
+	defaultConfidenceLevel     = 0.95
+	defaultBootstrapResamples  = 2000
+	minBootstrapResamplesCount = 100
+)
+
+type (
+	// confidenceIntervals configures the bootstrap confidence intervals of the statistics
+	confidenceIntervals struct {
+		Level     float64 `json:"level"`
+		Resamples int     `json:"resamples"`
+		Seed      *int64  `json:"seed"`
+	}
+
+	// confidenceInterval is the interval of an estimate
+	confidenceInterval struct {
+		Lower float64 `json:"lower"`
+		Upper float64 `json:"upper"`
+	}
+
+	// confidenceIntervalsResult contains the confidence intervals of a scenario
+	confidenceIntervalsResult struct {
+		Level     float64            `json:"level"`
+		Resamples int                `json:"resamples"`
+		Seed      int64              `json:"seed"`
+		Mean      confidenceInterval `json:"mean"`
+		Median    confidenceInterval `json:"median"`
+		P99       confidenceInterval `json:"p99"`
+		P95       confidenceInterval `json:"p95"`
+		P90       confidenceInterval `json:"p90"`
+	}
+)
+
+func (c *confidenceIntervals) validate() error {
+	if c.Level == 0 {
+		c.Level = defaultConfidenceLevel
+	} else if c.Level <= 0 || c.Level >= 1 {
+		return errors.New("confidence intervals level must be between 0 and 1")
+	}
+	if c.Resamples == 0 {
+		c.Resamples = defaultBootstrapResamples
+	} else if c.Resamples < minBootstrapResamplesCount {
+		return errors.New("confidence intervals resamples must be greater or equal than 100")
+	}
+	if c.Seed == nil {
+		seed := time.Now().UnixNano()
+		c.Seed = &seed
+	}
+	return nil
+}
+
+// setConfidenceIntervals calculates the confidence intervals of the mean, the median and the percentiles
+// using the percentile bootstrap, each scenario uses its own generator so the intervals can be reproduced
+func (r *scenarioResult) setConfidenceIntervals(ci *confidenceIntervals) {
+	values := r.DataFloat
+	if ci == nil || len(values) < 2 {
+		return
+	}
+
+	rnd := rand.New(rand.NewSource(*ci.Seed))
+	means := make([]float64, ci.Resamples)
+	medians := make([]float64, ci.Resamples)
+	p99s := make([]float64, ci.Resamples)
+	p95s := make([]float64, ci.Resamples)
+	p90s := make([]float64, ci.Resamples)
+	sample := make([]float64, len(values))
+	for i := 0; i < ci.Resamples; i++ {
+		for j := range sample {
+			sample[j] = values[rnd.Intn(len(values))]
+		}
+		// the sample is sorted once and all the estimates are read from it
+		sort.Float64s(sample)
+		means[i], _ = stats.Mean(sample)
+		medians[i] = sortedMedian(sample)
+		p99s[i] = sortedPercentile(sample, 99)
+		p95s[i] = sortedPercentile(sample, 95)
+		p90s[i] = sortedPercentile(sample, 90)
+	}
+
+	r.ConfidenceIntervals = &confidenceIntervalsResult{
+		Level:     ci.Level,
+		Resamples: ci.Resamples,
+		Seed:      *ci.Seed,
+		Mean:      getBootstrapInterval(means, ci.Level),
+		Median:    getBootstrapInterval(medians, ci.Level),
+		P99:       getBootstrapInterval(p99s, ci.Level),
+		P95:       getBootstrapInterval(p95s, ci.Level),
+		P90:       getBootstrapInterval(p90s, ci.Level),
+	}
+}
+
+// sortedMedian returns the median of sorted values, same as stats.Median
+func sortedMedian(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
+
+// sortedPercentile returns the percentile of sorted values, same as stats.Percentile
+// but the lowest value is returned when the percentile is below the first rank
+func sortedPercentile(sorted []float64, percent float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	index := (percent / 100) * float64(len(sorted))
+	i := int(index)
+	if i < 1 {
+		return sorted[0]
+	}
+	if index == float64(i) {
+		return sorted[i-1]
+	}
+	return (sorted[i-1] + sorted[i]) / 2
+}
+
+// String returns the interval as durations
+func (c confidenceInterval) String() string {
+	return fmt.Sprintf("[%v, %v]", time.Duration(c.Lower), time.Duration(c.Upper))
+}
+
+// getBootstrapInterval returns the interval between the tail quantiles of the bootstrap estimates,
+// the quantiles use the nearest rank clamped to the estimates so they are always defined
+func getBootstrapInterval(estimates []float64, level float64) confidenceInterval {
+	if len(estimates) == 0 {
+		return confidenceInterval{}
+	}
+	sorted := make([]float64, len(estimates))
+	copy(sorted, estimates)
+	sort.Float64s(sorted)
+
+	alpha := (1 - level) / 2
+	n := float64(len(sorted))
+	// the epsilon avoids off by one ranks from the rounding of the level (e.g. (1 - 0.9) / 2 * 100 = 4.999...)
+	const epsilon = 1e-9
+	lowerIndex := clampIndex(int(math.Floor(alpha*n+epsilon)), len(sorted))
+	upperIndex := clampIndex(int(math.Ceil((1-alpha)*n-epsilon))-1, len(sorted))
+	return confidenceInterval{Lower: sorted[lowerIndex], Upper: sorted[upperIndex]}
+}
+
+// clampIndex returns the index limited to the bounds of a slice of the given length
+func clampIndex(index int, length int) int {
+	if index < 0 {
+		return 0
+	}
+	if index >= length {
+		return length - 1
+	}
+	return index
+}
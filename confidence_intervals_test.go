@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/montanaflynn/stats"
+)
+
+func TestGetBootstrapInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		estimates []float64
+		level     float64
+		expected  confidenceInterval
+	}{
+		{name: "90%", estimates: sequence(1, 100), level: 0.9, expected: confidenceInterval{Lower: 6, Upper: 95}},
+		{name: "95%", estimates: sequence(1, 100), level: 0.95, expected: confidenceInterval{Lower: 3, Upper: 98}},
+		// fewer estimates than needed for the tails, the interval is clamped to the extremes
+		{name: "99% with few estimates", estimates: sequence(1, 100), level: 0.99, expected: confidenceInterval{Lower: 1, Upper: 100}},
+		{name: "unsorted", estimates: []float64{5, 1, 4, 2, 3}, level: 0.5, expected: confidenceInterval{Lower: 2, Upper: 4}},
+		{name: "single estimate", estimates: []float64{7}, level: 0.95, expected: confidenceInterval{Lower: 7, Upper: 7}},
+		{name: "empty", estimates: nil, level: 0.95, expected: confidenceInterval{}},
+	}
+	for _, test := range tests {
+		if actual := getBootstrapInterval(test.estimates, test.level); actual != test.expected {
+			t.Errorf("%s: interval = %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestSortedPercentile(t *testing.T) {
+	for _, values := range [][]float64{sequence(1, 2), sequence(1, 7), sequence(1, 10), sequence(1, 101)} {
+		for _, percent := range []float64{50, 90, 95, 99} {
+			expected, _ := stats.Percentile(values, percent)
+			if actual := sortedPercentile(values, percent); actual != expected {
+				t.Errorf("sortedPercentile(%d values, %v) = %v, expected %v", len(values), percent, actual, expected)
+			}
+		}
+		expected, _ := stats.Median(values)
+		if actual := sortedMedian(values); actual != expected {
+			t.Errorf("sortedMedian(%d values) = %v, expected %v", len(values), actual, expected)
+		}
+	}
+	// below the first rank stats.Percentile fails, the lowest value is used instead
+	if actual := sortedPercentile(sequence(1, 5), 10); actual != 1 {
+		t.Errorf("sortedPercentile below the first rank = %v, expected 1", actual)
+	}
+}
+
+func TestSetConfidenceIntervals(t *testing.T) {
+	seed := int64(42)
+	tests := []struct {
+		name      string
+		level     float64
+		resamples int
+	}{
+		{name: "defaults", level: 0.95, resamples: 2000},
+		{name: "high level with few resamples", level: 0.99, resamples: 100},
+	}
+	for _, test := range tests {
+		result := scenarioResult{DataFloat: sequence(1, 50)}
+		result.setConfidenceIntervals(&confidenceIntervals{Level: test.level, Resamples: test.resamples, Seed: &seed})
+		ci := result.ConfidenceIntervals
+		if ci == nil {
+			t.Fatalf("%s: confidence intervals not set", test.name)
+		}
+		for name, interval := range map[string]confidenceInterval{
+			"mean": ci.Mean, "median": ci.Median, "p99": ci.P99, "p95": ci.P95, "p90": ci.P90,
+		} {
+			if math.IsNaN(interval.Lower) || math.IsNaN(interval.Upper) || interval.Lower > interval.Upper {
+				t.Errorf("%s: invalid %s interval %v", test.name, name, interval)
+			}
+		}
+		if ci.Mean.Lower > 25.5 || ci.Mean.Upper < 25.5 {
+			t.Errorf("%s: mean interval %v doesn't contain the mean", test.name, ci.Mean)
+		}
+		if _, err := json.Marshal(ci); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+
+		// the same seed reproduces the same intervals
+		other := scenarioResult{DataFloat: sequence(1, 50)}
+		other.setConfidenceIntervals(&confidenceIntervals{Level: test.level, Resamples: test.resamples, Seed: &seed})
+		if *other.ConfidenceIntervals != *ci {
+			t.Errorf("%s: intervals are not reproducible with the same seed", test.name)
+		}
+	}
+
+	result := scenarioResult{DataFloat: []float64{1}}
+	result.setConfidenceIntervals(&confidenceIntervals{Level: 0.95, Resamples: 100, Seed: &seed})
+	if result.ConfidenceIntervals != nil {
+		t.Errorf("confidence intervals set for a single value")
+	}
+}
+
+func TestConfidenceIntervalsValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		ci    confidenceIntervals
+		valid bool
+	}{
+		{name: "defaults", ci: confidenceIntervals{}, valid: true},
+		{name: "level", ci: confidenceIntervals{Level: 0.99}, valid: true},
+		{name: "level too high", ci: confidenceIntervals{Level: 1}, valid: false},
+		{name: "negative level", ci: confidenceIntervals{Level: -0.5}, valid: false},
+		{name: "few resamples", ci: confidenceIntervals{Resamples: 10}, valid: false},
+	}
+	for _, test := range tests {
+		err := test.ci.validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: validate() = %v", test.name, err)
+		}
+		if err == nil && (test.ci.Level == 0 || test.ci.Resamples == 0 || test.ci.Seed == nil) {
+			t.Errorf("%s: defaults not set: %+v", test.name, test.ci)
+		}
+	}
+}
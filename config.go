@@ -3,50 +3,149 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type (
 	timeout struct {
-		MaxDuration      int     `json:"maxDuration"`
-		ProcessName      *string `json:"processName"`
-		ProcessArguments *string `json:"processArguments"`
+		MaxDuration      int        `json:"maxDuration"`
+		ProcessName      *string    `json:"processName"`
+		ProcessArguments *arguments `json:"processArguments"`
+		Shell            *bool      `json:"shell"`
+		GracePeriod      int        `json:"gracePeriod"`
 	}
 	processData struct {
-		ProcessName          *string           `json:"processName"`
-		ProcessArguments     *string           `json:"processArguments"`
-		WorkingDirectory     *string           `json:"workingDirectory"`
-		EnvironmentVariables map[string]string `json:"environmentVariables"`
-		Timeout              timeout           `json:"timeout"`
-		Tags                 map[string]string `json:"tags"`
-		MetricsFilePath      *string           `json:"metricsFilePath"`
+		ProcessName               *string           `json:"processName"`
+		ProcessArguments          *arguments        `json:"processArguments"`
+		Shell                     *bool             `json:"shell"`
+		WorkingDirectory          *string           `json:"workingDirectory"`
+		EnvironmentVariables      map[string]string `json:"environmentVariables"`
+		InheritEnvironment        *bool             `json:"inheritEnvironment"`
+		PassEnvironmentVariables  []string          `json:"passEnvironmentVariables"`
+		UnsetEnvironmentVariables []string          `json:"unsetEnvironmentVariables"`
+		Timeout                   timeout           `json:"timeout"`
+		Tags                      map[string]string `json:"tags"`
+		MetricsFilePath           *string           `json:"metricsFilePath"`
+		Setup                     []command         `json:"setup"`
+		Teardown                  []command         `json:"teardown"`
+		BeforeEach                []command         `json:"beforeEach"`
+		AfterEach                 []command         `json:"afterEach"`
+		ExpectedExitCode          exitCodes         `json:"expectedExitCode"`
+		Stdout                    *outputAssertion  `json:"stdout"`
+		Stderr                    *outputAssertion  `json:"stderr"`
+		Artifacts                 *artifacts        `json:"artifacts"`
+		CPUAffinity               []int             `json:"cpuAffinity"`
+		Niceness                  *int              `json:"niceness"`
+		FailFast                  *bool             `json:"failFast"`
+		MaxFailures               *int              `json:"maxFailures"`
+		MaxFailureRatio           *float64          `json:"maxFailureRatio"`
+		Retries                   int               `json:"retries"`
+		Stdin                     *string           `json:"stdin"`
+		StdinFile                 *string           `json:"stdinFile"`
+		Readiness                 *readiness        `json:"readiness"`
+		Service                   *service          `json:"service"`
+		HTTPProbe                 *httpProbe        `json:"httpProbe"`
+		Sampling                  *sampling         `json:"sampling"`
+		PerfCounters              []string          `json:"perfCounters"`
+		OutlierPolicy             *outlierPolicy    `json:"outliers"`
+	}
+	adaptive struct {
+		Criterion       string  `json:"criterion"`
+		Target          float64 `json:"target"`
+		ConfidenceLevel float64 `json:"confidenceLevel"`
+		MinCount        int     `json:"minCount"`
+		MaxCount        int     `json:"maxCount"`
+		MaxDuration     int     `json:"maxDuration"`
+	}
+	autoWarmUp struct {
+		Window    int     `json:"window"`
+		Threshold float64 `json:"threshold"`
+		MaxCount  int     `json:"maxCount"`
 	}
 	scenario struct {
 		processData
-		Name string `json:"name"`
+		Name           string      `json:"name"`
+		WarmUpCount    *int        `json:"warmUpCount"`
+		WarmUpDuration *int        `json:"warmUpDuration"`
+		Count          *int        `json:"count"`
+		Adaptive       *adaptive   `json:"adaptive"`
+		AutoWarmUp     *autoWarmUp `json:"autoWarmUp"`
+		MaxRunTime     *int        `json:"maxRunTime"`
+		MinRunTime     *int        `json:"minRunTime"`
 	}
 	config struct {
 		processData
 		FilePath             string
 		Path                 string
 		FileName             string
-		WarmUpCount          int        `json:"warmUpCount"`
-		Count                int        `json:"count"`
-		EnableDatadog        bool       `json:"enableDatadog"`
-		Scenarios            []scenario `json:"scenarios"`
-		JsonExporterFilePath string     `json:"jsonExporterFilePath"`
+		WarmUpCount          int                  `json:"warmUpCount"`
+		WarmUpDuration       int                  `json:"warmUpDuration"`
+		AutoWarmUp           *autoWarmUp          `json:"autoWarmUp"`
+		Count                int                  `json:"count"`
+		Adaptive             *adaptive            `json:"adaptive"`
+		MaxRunTime           int                  `json:"maxRunTime"`
+		MinRunTime           int                  `json:"minRunTime"`
+		MaxTotalRunTime      int                  `json:"maxTotalRunTime"`
+		Calibration          *calibration         `json:"calibration"`
+		ConfidenceIntervals  *confidenceIntervals `json:"confidenceIntervals"`
+		EnableDatadog        bool                 `json:"enableDatadog"`
+		Scenarios            []scenario           `json:"scenarios"`
+		Matrix               *matrix              `json:"matrix"`
+		JsonExporterFilePath string               `json:"jsonExporterFilePath"`
+		ExecutionOrder       string               `json:"executionOrder"`
+		Seed                 *int64               `json:"seed"`
+		Baseline             string               `json:"baseline"`
 	}
 )
 
-func loadConfiguration() (*config, error) {
-	if len(os.Args) < 2 {
+// commandLineOptions contains the options passed in the command line
+type commandLineOptions struct {
+	configurationFilePath string
+	progressMode          string
+	quiet                 bool
+}
+
+// parseCommandLine parses the command line arguments: the configuration file and the
+// --quiet, --verbose and --progress=<tty|plain> flags
+func parseCommandLine(args []string) (*commandLineOptions, error) {
+	options := &commandLineOptions{}
+	for _, arg := range args {
+		switch {
+		case arg == "--quiet" || arg == "-q":
+			options.quiet = true
+		case arg == "--verbose" || arg == "-v":
+			options.progressMode = progressModeVerbose
+		case strings.HasPrefix(arg, "--progress="):
+			options.progressMode = strings.TrimPrefix(arg, "--progress=")
+			if options.progressMode != progressModeTTY && options.progressMode != progressModePlain {
+				return nil, fmt.Errorf("invalid progress mode '%s', valid values are: %s, %s", options.progressMode, progressModeTTY, progressModePlain)
+			}
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("unknown option '%s'", arg)
+		default:
+			if options.configurationFilePath != "" {
+				return nil, fmt.Errorf("unexpected argument '%s'", arg)
+			}
+			options.configurationFilePath = arg
+		}
+	}
+	if options.quiet {
+		if options.progressMode == progressModeVerbose {
+			return nil, errors.New("--quiet and --verbose can't be used together")
+		}
+		options.progressMode = progressModeQuiet
+	}
+	if options.configurationFilePath == "" {
 		return nil, errors.New("missing argument with the configuration file")
 	}
+	return options, nil
+}
 
-	configurationFilePath := os.Args[1]
-
+func loadConfiguration(configurationFilePath string) (*config, error) {
 	jsonFile, err := os.Open(configurationFilePath)
 	if err != nil {
 		return nil, err
@@ -64,9 +163,223 @@ func loadConfiguration() (*config, error) {
 		return nil, err
 	}
 
+	switch cfg.ExecutionOrder {
+	case "", executionOrderSequential, executionOrderRoundRobin, executionOrderShuffled:
+	default:
+		return nil, fmt.Errorf("invalid execution order '%s', valid values are: %s, %s, %s",
+			cfg.ExecutionOrder, executionOrderSequential, executionOrderRoundRobin, executionOrderShuffled)
+	}
+
+	if cfg.ExecutionOrder != "" && cfg.ExecutionOrder != executionOrderSequential {
+		hasService := cfg.Service != nil
+		for _, sce := range cfg.Scenarios {
+			hasService = hasService || sce.Service != nil
+		}
+		if hasService {
+			return nil, fmt.Errorf("scenarios with a service require the '%s' execution order", executionOrderSequential)
+		}
+	}
+
+	if cfg.Adaptive != nil {
+		if err = cfg.Adaptive.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Matrix != nil {
+		if err = cfg.Matrix.validate(); err != nil {
+			return nil, err
+		}
+		names := map[string]bool{}
+		for _, sce := range cfg.Scenarios {
+			names[sce.Name] = true
+		}
+		for _, sce := range cfg.Matrix.expand(&cfg.processData) {
+			if names[sce.Name] {
+				return nil, fmt.Errorf("duplicated scenario name '%s' generated by the matrix", sce.Name)
+			}
+			names[sce.Name] = true
+			cfg.Scenarios = append(cfg.Scenarios, sce)
+		}
+	}
+
+	if cfg.AutoWarmUp != nil {
+		if err = cfg.AutoWarmUp.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Calibration != nil {
+		if err = cfg.Calibration.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Baseline != "" {
+		found := false
+		for _, sce := range cfg.Scenarios {
+			found = found || sce.Name == cfg.Baseline
+		}
+		if !found {
+			return nil, fmt.Errorf("baseline scenario '%s' not found", cfg.Baseline)
+		}
+	}
+
+	if cfg.ConfidenceIntervals == nil {
+		cfg.ConfidenceIntervals = &confidenceIntervals{}
+	}
+	if err = cfg.ConfidenceIntervals.validate(); err != nil {
+		return nil, err
+	}
+
+	if err = cfg.validate(); err != nil {
+		return nil, err
+	}
+	if err = validateRunTime(cfg.MaxRunTime, cfg.MinRunTime); err != nil {
+		return nil, err
+	}
+	if cfg.MaxTotalRunTime < 0 {
+		return nil, errors.New("maxTotalRunTime must be greater or equal than 0")
+	}
+	if err = validateArtifactsFolders(&cfg); err != nil {
+		return nil, err
+	}
+	for idx := range cfg.Scenarios {
+		maxRunTime, minRunTime := cfg.MaxRunTime, cfg.MinRunTime
+		if cfg.Scenarios[idx].MaxRunTime != nil {
+			maxRunTime = *cfg.Scenarios[idx].MaxRunTime
+		}
+		if cfg.Scenarios[idx].MinRunTime != nil {
+			minRunTime = *cfg.Scenarios[idx].MinRunTime
+		}
+		if err = validateRunTime(maxRunTime, minRunTime); err != nil {
+			return nil, fmt.Errorf("scenario '%s': %v", cfg.Scenarios[idx].Name, err)
+		}
+		if err = cfg.Scenarios[idx].validate(); err != nil {
+			return nil, fmt.Errorf("scenario '%s': %v", cfg.Scenarios[idx].Name, err)
+		}
+		if cfg.Scenarios[idx].Adaptive != nil {
+			if err = cfg.Scenarios[idx].Adaptive.validate(); err != nil {
+				return nil, fmt.Errorf("scenario '%s': %v", cfg.Scenarios[idx].Name, err)
+			}
+		}
+		if cfg.Scenarios[idx].AutoWarmUp != nil {
+			if err = cfg.Scenarios[idx].AutoWarmUp.validate(); err != nil {
+				return nil, fmt.Errorf("scenario '%s': %v", cfg.Scenarios[idx].Name, err)
+			}
+		}
+	}
+
 	cfg.FilePath = configurationFilePath
 	cfg.Path = filepath.Dir(configurationFilePath)
 	cfg.FileName = filepath.Base(configurationFilePath)
 
 	return &cfg, nil
 }
+
+// hasIterations returns true if any scenario has iterations to run
+func (c *config) hasIterations() bool {
+	if c.Count > 0 || c.Adaptive != nil || c.MaxRunTime > 0 || c.MinRunTime > 0 {
+		return len(c.Scenarios) > 0
+	}
+	for _, sce := range c.Scenarios {
+		if (sce.Count != nil && *sce.Count > 0) || sce.Adaptive != nil ||
+			(sce.MaxRunTime != nil && *sce.MaxRunTime > 0) || (sce.MinRunTime != nil && *sce.MinRunTime > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *adaptive) validate() error {
+	switch a.Criterion {
+	case "":
+		a.Criterion = adaptiveCriterionStdErr
+	case adaptiveCriterionStdErr, adaptiveCriterionConfidenceInterval:
+	default:
+		return fmt.Errorf("invalid adaptive criterion '%s', valid values are: %s, %s",
+			a.Criterion, adaptiveCriterionStdErr, adaptiveCriterionConfidenceInterval)
+	}
+	if a.Target <= 0 {
+		return errors.New("adaptive target must be greater than 0")
+	}
+	if a.ConfidenceLevel == 0 {
+		a.ConfidenceLevel = 0.95
+	} else if a.ConfidenceLevel <= 0 || a.ConfidenceLevel >= 1 {
+		return errors.New("adaptive confidence level must be between 0 and 1")
+	}
+	if a.MinCount < 2 {
+		a.MinCount = 2
+	}
+	if a.MaxCount < a.MinCount {
+		return errors.New("adaptive maxCount must be greater or equal than minCount")
+	}
+	return nil
+}
+
+func validateRunTime(maxRunTime int, minRunTime int) error {
+	if maxRunTime < 0 || minRunTime < 0 {
+		return errors.New("maxRunTime and minRunTime must be greater or equal than 0")
+	}
+	if maxRunTime > 0 && minRunTime > maxRunTime {
+		return errors.New("minRunTime must be less or equal than maxRunTime")
+	}
+	return nil
+}
+
+func (a *autoWarmUp) validate() error {
+	if a.Window == 0 {
+		a.Window = 10
+	} else if a.Window < 3 {
+		return errors.New("autoWarmUp window must be greater or equal than 3")
+	}
+	if a.Threshold == 0 {
+		a.Threshold = 0.05
+	} else if a.Threshold < 0 {
+		return errors.New("autoWarmUp threshold must be greater than 0")
+	}
+	if a.MaxCount == 0 {
+		a.MaxCount = 100
+	}
+	if a.MaxCount < a.Window {
+		return errors.New("autoWarmUp maxCount must be greater or equal than window")
+	}
+	return nil
+}
+
+func (p *processData) validate() error {
+	if err := p.Stdout.compile(); err != nil {
+		return err
+	}
+	if err := p.Stderr.compile(); err != nil {
+		return err
+	}
+	if err := p.validateScheduling(); err != nil {
+		return err
+	}
+	if err := p.validateFailurePolicy(); err != nil {
+		return err
+	}
+	if err := p.validateStdin(); err != nil {
+		return err
+	}
+	if err := p.validateEnvironment(); err != nil {
+		return err
+	}
+	if err := p.Readiness.compile(); err != nil {
+		return err
+	}
+	if err := p.Service.validate(); err != nil {
+		return err
+	}
+	if err := p.Sampling.validate(); err != nil {
+		return err
+	}
+	if err := p.validatePerfCounters(); err != nil {
+		return err
+	}
+	if err := p.OutlierPolicy.validate(); err != nil {
+		return err
+	}
+	return p.HTTPProbe.validate()
+}
@@ -43,12 +43,15 @@ func (de *datadogExporter) Export(resScenario []scenarioResult) {
 			}
 
 			if scenario.ProcessArguments != nil {
-				pArgs = *scenario.ProcessArguments
+				pArgs = scenario.ProcessArguments.String()
 			} else if cfg.ProcessArguments != nil {
-				pArgs = *cfg.ProcessArguments
+				pArgs = cfg.ProcessArguments.String()
 			}
 
-			tags = cfg.Tags
+			tags = map[string]string{}
+			for k, v := range cfg.Tags {
+				tags[k] = v
+			}
 			for k, v := range scenario.Tags {
 				tags[k] = v
 			}
@@ -56,11 +59,15 @@ func (de *datadogExporter) Export(resScenario []scenarioResult) {
 			var startSpanOptions []tracer.StartSpanOption
 			startSpanOptions = append(startSpanOptions, tracer.StartTime(scenario.Start))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.job.description", scenario.Name))
-			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.runs", cfg.Count))
-			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.warmup_count", cfg.WarmUpCount))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.runs", scenario.Count))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.warmup_count", scenario.WarmUpCount))
+			if scenario.WarmUpStopReason != "" {
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.warmup_stop_reason", scenario.WarmUpStopReason))
+			}
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.duration.mean", scenario.Mean))
-			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.n", cfg.Count))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.n", scenario.Count))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.mean", scenario.Mean))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.median", scenario.Median))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.max", scenario.Max))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.min", scenario.Min))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.std_dev", scenario.Stdev))
@@ -68,7 +75,58 @@ func (de *datadogExporter) Export(resScenario []scenarioResult) {
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.p90", scenario.P90))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.p95", scenario.P95))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.p99", scenario.P99))
+			if ci := scenario.ConfidenceIntervals; ci != nil {
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.ci.level", ci.Level))
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.ci.resamples", ci.Resamples))
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.ci.seed", ci.Seed))
+				for name, interval := range map[string]confidenceInterval{
+					"mean": ci.Mean, "median": ci.Median, "p99": ci.P99, "p95": ci.P95, "p90": ci.P90,
+				} {
+					startSpanOptions = append(startSpanOptions, tracer.Tag(fmt.Sprintf("benchmark.statistics.ci.%v.lower", name), interval.Lower))
+					startSpanOptions = append(startSpanOptions, tracer.Tag(fmt.Sprintf("benchmark.statistics.ci.%v.upper", name), interval.Upper))
+				}
+			}
 			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.outliers", len(scenario.Outliers)))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.outlier_policy", scenario.OutlierPolicy))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.timeouts", scenario.Timeouts))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.failures", scenario.Failures))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.statistics.retries", scenario.Retries))
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.stop_reason", scenario.StopReason))
+			if scenario.Precision != nil {
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.precision", *scenario.Precision))
+			}
+			if c := scenario.Comparison; c != nil {
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.comparison.baseline", c.Baseline))
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.comparison.result", c.Result))
+				for name, diff := range map[string]comparisonDifference{"mean": c.Mean, "median": c.Median} {
+					startSpanOptions = append(startSpanOptions, tracer.Tag(fmt.Sprintf("benchmark.comparison.%v.diff", name), diff.Difference))
+					startSpanOptions = append(startSpanOptions, tracer.Tag(fmt.Sprintf("benchmark.comparison.%v.diff.lower", name), diff.DifferenceInterval.Lower))
+					startSpanOptions = append(startSpanOptions, tracer.Tag(fmt.Sprintf("benchmark.comparison.%v.diff.upper", name), diff.DifferenceInterval.Upper))
+					startSpanOptions = append(startSpanOptions, tracer.Tag(fmt.Sprintf("benchmark.comparison.%v.relative", name), diff.Relative))
+				}
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.comparison.welch.p_value", c.WelchPValue))
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.comparison.mann_whitney.p_value", c.MannWhitneyPValue))
+			}
+			startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.execution_order", scenario.ExecutionOrder))
+			if scenario.Seed != nil {
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.seed", *scenario.Seed))
+			}
+			if scenario.Scheduling != nil {
+				if len(scenario.Scheduling.CPUAffinity) > 0 {
+					startSpanOptions = append(startSpanOptions, tracer.Tag("process.cpu_affinity", fmt.Sprint(scenario.Scheduling.CPUAffinity)))
+				}
+				if scenario.Scheduling.Niceness != nil {
+					startSpanOptions = append(startSpanOptions, tracer.Tag("process.niceness", *scenario.Scheduling.Niceness))
+				}
+			}
+			if scenario.ServiceStartupTime != nil {
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.service.startup_time", float64(*scenario.ServiceStartupTime)))
+			}
+			if scenario.Calibration != nil {
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.calibration.mean", scenario.Calibration.Mean))
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.calibration.std_err", scenario.Calibration.StdErr))
+				startSpanOptions = append(startSpanOptions, tracer.Tag("benchmark.calibration.subtracted", scenario.Calibration.Subtracted))
+			}
 			startSpanOptions = append(startSpanOptions, tracer.Tag("process.name", pName))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("process.arguments", pArgs))
 			startSpanOptions = append(startSpanOptions, tracer.Tag("test.file.path", cfg.Path))
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+const redactedValue = "[redacted]"
+
+// secretVariableName matches the names of the environment variables whose values are not recorded in the results
+var secretVariableName = regexp.MustCompile(`(?i)(key|secret|token|passw(or)?d|credential|auth|private|cookie|session|signature)`)
+
+func (p *processData) validateEnvironment() error {
+	for _, pattern := range append(append([]string{}, p.PassEnvironmentVariables...), p.UnsetEnvironmentVariables...) {
+		if err := validatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return errors.New(fmt.Sprintf("invalid pattern '%s'", pattern))
+	}
+	return nil
+}
+
+// inheritsEnvironmentVariable returns true if a variable of the current process is passed to the child processes
+func (p *processData) inheritsEnvironmentVariable(name string) bool {
+	if p.InheritEnvironment != nil && !*p.InheritEnvironment {
+		return matchesAnyPattern(name, p.PassEnvironmentVariables)
+	}
+	return true
+}
+
+// buildEnvironment returns the inherited environment of the current process with the variables appended,
+// the unset patterns are applied after the merge so they also remove the configured variables
+func buildEnvironment(p *processData) []string {
+	// the slice is never nil, a nil environment makes exec.Cmd inherit the whole environment
+	env := []string{}
+	for _, item := range os.Environ() {
+		if p.inheritsEnvironmentVariable(environmentVariableName(item)) {
+			env = append(env, item)
+		}
+	}
+	for k, v := range p.EnvironmentVariables {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	if len(p.UnsetEnvironmentVariables) == 0 {
+		return env
+	}
+	filtered := []string{}
+	for _, item := range env {
+		if !matchesAnyPattern(environmentVariableName(item), p.UnsetEnvironmentVariables) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// getEffectiveEnvironment returns the environment of the child processes with the secret values redacted
+func getEffectiveEnvironment(p *processData) map[string]string {
+	effective := map[string]string{}
+	// later values override the previous ones, as in exec.Cmd
+	for _, item := range buildEnvironment(p) {
+		name := environmentVariableName(item)
+		value := strings.TrimPrefix(item[len(name):], "=")
+		if secretVariableName.MatchString(name) {
+			value = redactedValue
+		}
+		effective[name] = value
+	}
+	return effective
+}
+
+func environmentVariableName(item string) string {
+	if item == "" {
+		return item
+	}
+	// on Windows some variable names start with '=' (e.g. "=C:=C:\")
+	if idx := strings.Index(item[1:], "="); idx >= 0 {
+		return item[:idx+1]
+	}
+	return item
+}
+
+// matchesAnyPattern returns true if the variable name matches any of the patterns,
+// the names are compared without case on Windows
+func matchesAnyPattern(name string, patterns []string) bool {
+	if runtime.GOOS == "windows" {
+		name = strings.ToUpper(name)
+	}
+	for _, pattern := range patterns {
+		if runtime.GOOS == "windows" {
+			pattern = strings.ToUpper(pattern)
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
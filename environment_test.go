@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestBuildEnvironment(t *testing.T) {
+	variables := map[string]string{"TIMEIT_TEST_KEEP": "1", "TIMEIT_TEST_DROP": "2", "TIMEIT_TEST_SECRET_TOKEN": "3"}
+	for k, v := range variables {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	disabled := false
+
+	tests := []struct {
+		name     string
+		data     processData
+		expected map[string]string
+		absent   []string
+	}{
+		{
+			name:     "inherit",
+			data:     processData{},
+			expected: map[string]string{"TIMEIT_TEST_KEEP": "1", "TIMEIT_TEST_DROP": "2"},
+		},
+		{
+			name:     "unset inherited",
+			data:     processData{UnsetEnvironmentVariables: []string{"TIMEIT_TEST_D*"}},
+			expected: map[string]string{"TIMEIT_TEST_KEEP": "1"},
+			absent:   []string{"TIMEIT_TEST_DROP"},
+		},
+		{
+			name: "unset configured",
+			data: processData{
+				EnvironmentVariables:      map[string]string{"TIMEIT_TEST_DEBUG": "1", "TIMEIT_TEST_MODE": "fast"},
+				UnsetEnvironmentVariables: []string{"TIMEIT_TEST_D*"},
+			},
+			expected: map[string]string{"TIMEIT_TEST_KEEP": "1", "TIMEIT_TEST_MODE": "fast"},
+			absent:   []string{"TIMEIT_TEST_DROP", "TIMEIT_TEST_DEBUG"},
+		},
+		{
+			name: "pass only",
+			data: processData{
+				InheritEnvironment:       &disabled,
+				PassEnvironmentVariables: []string{"TIMEIT_TEST_K*"},
+				EnvironmentVariables:     map[string]string{"TIMEIT_TEST_MODE": "fast"},
+			},
+			expected: map[string]string{"TIMEIT_TEST_KEEP": "1", "TIMEIT_TEST_MODE": "fast"},
+			absent:   []string{"TIMEIT_TEST_DROP", "PATH"},
+		},
+		{
+			name:     "configured overrides inherited",
+			data:     processData{EnvironmentVariables: map[string]string{"TIMEIT_TEST_KEEP": "override"}},
+			expected: map[string]string{"TIMEIT_TEST_KEEP": "override"},
+		},
+		{
+			name:     "secrets redacted",
+			data:     processData{},
+			expected: map[string]string{"TIMEIT_TEST_SECRET_TOKEN": redactedValue},
+		},
+	}
+	for _, test := range tests {
+		effective := getEffectiveEnvironment(&test.data)
+		for k, v := range test.expected {
+			if effective[k] != v {
+				t.Errorf("%s: %s = %q, expected %q", test.name, k, effective[k], v)
+			}
+		}
+		for _, k := range test.absent {
+			if _, ok := effective[k]; ok {
+				t.Errorf("%s: %s should not be set", test.name, k)
+			}
+		}
+	}
+
+	// a fully filtered environment must not be nil, exec.Cmd would inherit everything
+	env := buildEnvironment(&processData{InheritEnvironment: &disabled})
+	if env == nil || len(env) != 0 {
+		t.Errorf("empty environment = %#v, expected an empty slice", env)
+	}
+}
+
+func TestMatchesAnyPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		expected bool
+	}{
+		{name: "DD_API_KEY", patterns: []string{"DD_*"}, expected: true},
+		{name: "DD_API_KEY", patterns: []string{"PATH", "DD_API_?EY"}, expected: true},
+		{name: "PATH", patterns: []string{"DD_*"}, expected: false},
+		{name: "PATH", patterns: nil, expected: false},
+		{name: "dd_api_key", patterns: []string{"DD_*"}, expected: runtime.GOOS == "windows"},
+	}
+	for _, test := range tests {
+		if actual := matchesAnyPattern(test.name, test.patterns); actual != test.expected {
+			t.Errorf("matchesAnyPattern(%s, %v) = %v, expected %v", test.name, test.patterns, actual, test.expected)
+		}
+	}
+}
+
+func TestEnvironmentVariableName(t *testing.T) {
+	tests := []struct {
+		item     string
+		expected string
+	}{
+		{item: "PATH=/bin", expected: "PATH"},
+		{item: "EMPTY=", expected: "EMPTY"},
+		{item: "A=B=C", expected: "A"},
+		{item: "=C:=C:\\dir", expected: "=C:"},
+		{item: "", expected: ""},
+	}
+	for _, test := range tests {
+		if actual := environmentVariableName(test.item); actual != test.expected {
+			t.Errorf("environmentVariableName(%q) = %q, expected %q", test.item, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func (p *processData) validateFailurePolicy() error {
+	if p.MaxFailures != nil && *p.MaxFailures < 0 {
+		return errors.New("maxFailures must be greater or equal than 0")
+	}
+	if p.MaxFailureRatio != nil && (*p.MaxFailureRatio < 0 || *p.MaxFailureRatio > 1) {
+		return errors.New("maxFailureRatio must be between 0 and 1")
+	}
+	if p.Retries < 0 {
+		return errors.New("retries must be greater or equal than 0")
+	}
+	return nil
+}
+
+// checkFailurePolicy sets the stop reason of the run if the failures exceed the configured limits
+func (r *scenarioRun) checkFailurePolicy() {
+	sce := r.scenario
+	if sce.FailFast != nil && *sce.FailFast {
+		r.stopReason = stopReasonFailFast
+		return
+	}
+	if sce.MaxFailures != nil && r.failures > *sce.MaxFailures {
+		r.stopReason = stopReasonMaxFailures
+		return
+	}
+	if sce.MaxFailureRatio != nil {
+		// stop as soon as the ratio can't be satisfied by the planned number of iterations,
+		// runs limited only by time budgets have no planned count and are checked at the end
+		planned := r.count
+		if r.adaptive != nil {
+			planned = r.adaptive.MaxCount
+		}
+		if planned > 0 && float64(r.failures) > *sce.MaxFailureRatio*float64(planned) {
+			r.stopReason = stopReasonMaxFailureRatio
+		}
+	}
+}
+
+// getFailurePolicyError returns an error if the scenario failures are not tolerated by the failure policy
+func getFailurePolicyError(sce *scenario, failures int, total int, stopReason string) error {
+	switch stopReason {
+	case stopReasonFailFast:
+		return errors.New("the scenario was stopped by failFast")
+	case stopReasonMaxFailures:
+		return fmt.Errorf("the scenario was stopped after exceeding maxFailures (%d)", *sce.MaxFailures)
+	case stopReasonMaxFailureRatio:
+		return fmt.Errorf("the scenario was stopped after exceeding maxFailureRatio (%v)", *sce.MaxFailureRatio)
+	case stopReasonError:
+		return fmt.Errorf("the scenario was stopped by an error after %d iterations", total)
+	}
+
+	if failures == 0 {
+		return nil
+	}
+	if sce.MaxFailures == nil && sce.MaxFailureRatio == nil {
+		return fmt.Errorf("%d of %d iterations failed", failures, total)
+	}
+	if sce.MaxFailures != nil && failures > *sce.MaxFailures {
+		return fmt.Errorf("%d of %d iterations failed, exceeding maxFailures (%d)", failures, total, *sce.MaxFailures)
+	}
+	if sce.MaxFailureRatio != nil && total > 0 && float64(failures)/float64(total) > *sce.MaxFailureRatio {
+		return fmt.Errorf("%d of %d iterations failed, exceeding maxFailureRatio (%v)", failures, total, *sce.MaxFailureRatio)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckFailurePolicy(t *testing.T) {
+	failFast := true
+	maxFailures := 2
+	maxFailureRatio := 0.3
+	tests := []struct {
+		name       string
+		policy     processData
+		count      int
+		adaptive   *adaptive
+		executed   int
+		failures   int
+		stopReason string
+	}{
+		{name: "failFast", policy: processData{FailFast: &failFast}, count: 10, executed: 1, failures: 1, stopReason: stopReasonFailFast},
+		{name: "maxFailures tolerated", policy: processData{MaxFailures: &maxFailures}, count: 10, executed: 2, failures: 2},
+		{name: "maxFailures exceeded", policy: processData{MaxFailures: &maxFailures}, count: 10, executed: 3, failures: 3, stopReason: stopReasonMaxFailures},
+		{name: "ratio tolerated", policy: processData{MaxFailureRatio: &maxFailureRatio}, count: 10, executed: 3, failures: 3},
+		{name: "ratio exceeded", policy: processData{MaxFailureRatio: &maxFailureRatio}, count: 10, executed: 4, failures: 4, stopReason: stopReasonMaxFailureRatio},
+		{name: "ratio adaptive", policy: processData{MaxFailureRatio: &maxFailureRatio}, adaptive: &adaptive{MaxCount: 20}, executed: 6, failures: 6},
+		{name: "ratio adaptive exceeded", policy: processData{MaxFailureRatio: &maxFailureRatio}, adaptive: &adaptive{MaxCount: 20}, executed: 7, failures: 7, stopReason: stopReasonMaxFailureRatio},
+		{name: "ratio without count", policy: processData{MaxFailureRatio: &maxFailureRatio}, executed: 1, failures: 1},
+		{name: "ratio without count all failed", policy: processData{MaxFailureRatio: &maxFailureRatio}, executed: 50, failures: 50},
+		{name: "no policy", count: 10, executed: 5, failures: 5},
+	}
+	for _, test := range tests {
+		run := &scenarioRun{
+			scenario: &scenario{processData: test.policy},
+			count:    test.count,
+			adaptive: test.adaptive,
+			data:     make([]scenarioDataPoint, test.executed),
+			failures: test.failures,
+		}
+		run.checkFailurePolicy()
+		if run.stopReason != test.stopReason {
+			t.Errorf("%s: stop reason = %q, expected %q", test.name, run.stopReason, test.stopReason)
+		}
+	}
+}
+
+func TestGetFailurePolicyError(t *testing.T) {
+	maxFailures := 2
+	maxFailureRatio := 0.3
+	tests := []struct {
+		name       string
+		policy     processData
+		failures   int
+		total      int
+		stopReason string
+		expected   string
+	}{
+		{name: "no failures", total: 10, stopReason: stopReasonCount},
+		{name: "failures without policy", failures: 1, total: 10, stopReason: stopReasonCount, expected: "1 of 10 iterations failed"},
+		{name: "failFast", failures: 1, total: 1, stopReason: stopReasonFailFast, expected: "the scenario was stopped by failFast"},
+		{name: "stopped by maxFailures", policy: processData{MaxFailures: &maxFailures}, failures: 3, total: 3, stopReason: stopReasonMaxFailures, expected: "the scenario was stopped after exceeding maxFailures (2)"},
+		{name: "stopped by maxFailureRatio", policy: processData{MaxFailureRatio: &maxFailureRatio}, failures: 4, total: 4, stopReason: stopReasonMaxFailureRatio, expected: "the scenario was stopped after exceeding maxFailureRatio (0.3)"},
+		{name: "stopped by an error", failures: 1, total: 3, stopReason: stopReasonError, expected: "the scenario was stopped by an error after 3 iterations"},
+		{name: "maxFailures tolerated", policy: processData{MaxFailures: &maxFailures}, failures: 2, total: 10, stopReason: stopReasonCount},
+		{name: "maxFailureRatio tolerated", policy: processData{MaxFailureRatio: &maxFailureRatio}, failures: 3, total: 10, stopReason: stopReasonMaxRunTime},
+		{name: "maxFailureRatio exceeded at the end", policy: processData{MaxFailureRatio: &maxFailureRatio}, failures: 4, total: 10, stopReason: stopReasonMaxRunTime, expected: "4 of 10 iterations failed, exceeding maxFailureRatio (0.3)"},
+	}
+	for _, test := range tests {
+		err := getFailurePolicyError(&scenario{processData: test.policy}, test.failures, test.total, test.stopReason)
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if actual != test.expected {
+			t.Errorf("%s: error = %q, expected %q", test.name, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// command is a hook command executed around the scenarios and iterations, outside the measured window
+type command struct {
+	ProcessName      *string    `json:"processName"`
+	ProcessArguments *arguments `json:"processArguments"`
+	Shell            *bool      `json:"shell"`
+}
+
+// prepareCommands returns a copy of the commands with the custom vars replaced
+func prepareCommands(commands []command) []command {
+	var prepared []command
+	for _, cmd := range commands {
+		if cmd.ProcessName != nil {
+			processName := replaceCustomVars(*cmd.ProcessName)
+			cmd.ProcessName = &processName
+		}
+		cmd.ProcessArguments = cmd.ProcessArguments.Replace(replaceCustomVars)
+		prepared = append(prepared, cmd)
+	}
+	return prepared
+}
+
+// runCommands runs the commands in order, stopping at the first one that fails
+func runCommands(commands []command, workingDirectory string, env []string) error {
+	for _, hook := range commands {
+		if hook.ProcessName == nil || *hook.ProcessName == "" {
+			continue
+		}
+
+		cmd := newCommand(context.Background(), *hook.ProcessName, hook.ProcessArguments, hook.Shell != nil && *hook.Shell)
+		cmd.Dir = workingDirectory
+		cmd.Env = env
+
+		var b bytes.Buffer
+		cmd.Stdout = &b
+		cmd.Stderr = &b
+		if err := cmd.Run(); err != nil {
+			cmdLine := strings.TrimSpace(fmt.Sprintf("%s %s", *hook.ProcessName, hook.ProcessArguments.String()))
+			return errors.New(fmt.Sprintf("'%s' failed:\n%s%s", cmdLine, b.String(), err.Error()))
+		}
+	}
+	return nil
+}
+
+// runScenarioCommands runs the commands using the scenario working directory and environment variables
+func runScenarioCommands(commands []command, sce *scenario) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	var workingDirectory string
+	if sce.WorkingDirectory != nil {
+		workingDirectory = *sce.WorkingDirectory
+	}
+	return runCommands(commands, workingDirectory, buildEnvironment(&sce.processData))
+}
+
+// runConfigCommands runs the commands using the configuration working directory and environment variables
+func runConfigCommands(commands []command, cfg *config) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	var workingDirectory string
+	if cfg.WorkingDirectory != nil {
+		workingDirectory = replaceCustomVars(*cfg.WorkingDirectory)
+	}
+	env := map[string]string{}
+	for k, v := range cfg.EnvironmentVariables {
+		env[k] = replaceCustomVars(v)
+	}
+	data := cfg.processData
+	data.EnvironmentVariables = env
+	return runCommands(prepareCommands(commands), workingDirectory, buildEnvironment(&data))
+}
@@ -7,8 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"os"
-	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
@@ -23,29 +23,58 @@ type (
 	scenarioResult struct {
 		scenario
 		scenarioDataPoint
-		WarmUpCount int                  `json:"warmUpCount"`
-		Count       int                  `json:"count"`
-		Data        []scenarioDataPoint  `json:"data"`
-		DataFloat   []float64            `json:"durations"`
-		Outliers    []float64            `json:"outliers"`
-		Mean        float64              `json:"mean"`
-		Max         float64              `json:"max"`
-		Min         float64              `json:"min"`
-		Stdev       float64              `json:"stdev"`
-		StdErr      float64              `json:"stderr"`
-		P99         float64              `json:"p99"`
-		P95         float64              `json:"p95"`
-		P90         float64              `json:"p90"`
-		Metrics     map[string]float64   `json:"metrics"`
-		MetricsData map[string][]float64 `json:"metricsData"`
+		WarmUpCount         int                        `json:"warmUpCount"`
+		WarmUpStopReason    string                     `json:"warmUpStopReason,omitempty"`
+		WarmUpDurations     []float64                  `json:"warmUpDurations,omitempty"`
+		Count               int                        `json:"count"`
+		Failed              bool                       `json:"failed"`
+		Timeouts            int                        `json:"timeouts"`
+		Failures            int                        `json:"failures"`
+		Retries             int                        `json:"retries"`
+		StopReason          string                     `json:"stopReason"`
+		Precision           *float64                   `json:"precision,omitempty"`
+		ExecutionOrder      string                     `json:"executionOrder"`
+		Seed                *int64                     `json:"seed,omitempty"`
+		Scheduling          *schedulingSettings        `json:"scheduling,omitempty"`
+		ServiceStartupTime  *time.Duration             `json:"serviceStartupTime,omitempty"`
+		Calibration         *calibrationResult         `json:"calibration,omitempty"`
+		Environment         map[string]string          `json:"environment,omitempty"`
+		Data                []scenarioDataPoint        `json:"data"`
+		DataFloat           []float64                  `json:"durations"`
+		Outliers            []float64                  `json:"outliers"`
+		OutlierIndexes      []int                      `json:"outlierIndexes"`
+		OutlierPolicy       string                     `json:"outlierPolicy"`
+		Mean                float64                    `json:"mean"`
+		Median              float64                    `json:"median"`
+		Max                 float64                    `json:"max"`
+		Min                 float64                    `json:"min"`
+		Stdev               float64                    `json:"stdev"`
+		StdErr              float64                    `json:"stderr"`
+		P99                 float64                    `json:"p99"`
+		P95                 float64                    `json:"p95"`
+		P90                 float64                    `json:"p90"`
+		ConfidenceIntervals *confidenceIntervalsResult `json:"confidenceIntervals"`
+		Comparison          *comparisonResult          `json:"comparison,omitempty"`
+		Metrics             map[string]float64         `json:"metrics"`
+		MetricsData         map[string][]float64       `json:"metricsData"`
 	}
 	scenarioDataPoint struct {
-		Start          time.Time     `json:"start"`
-		End            time.Time     `json:"end"`
-		Duration       time.Duration `json:"duration"`
-		Error          error         `json:"error"`
-		metrics        map[string]float64
-		shouldContinue bool
+		Start             time.Time          `json:"start"`
+		End               time.Time          `json:"end"`
+		Duration          time.Duration      `json:"duration"`
+		Status            string             `json:"status,omitempty"`
+		ExitCode          *int               `json:"exitCode,omitempty"`
+		Error             error              `json:"error"`
+		ResourceUsage     *resourceUsage     `json:"resourceUsage,omitempty"`
+		PerfCounters      map[string]float64 `json:"perfCounters,omitempty"`
+		TimeToFirstOutput *time.Duration     `json:"timeToFirstOutput,omitempty"`
+		TimeToReady       *time.Duration     `json:"timeToReady,omitempty"`
+		StdoutPath        string             `json:"stdoutPath,omitempty"`
+		StderrPath        string             `json:"stderrPath,omitempty"`
+		metrics           map[string]float64
+		stdout            []byte
+		stderr            []byte
+		shouldContinue    bool
 	}
 	metricsItem struct {
 		key   string
@@ -57,8 +86,18 @@ var currentWorkingDirectory *string
 var exporters []Exporter
 
 func main() {
-	fmt.Print("TimeIt by Tony Redondo\n\n")
-	cfg, err := loadConfiguration()
+	options, err := parseCommandLine(os.Args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+		return
+	}
+	quietMode = options.quiet
+	progress = newProgressRenderer(options.progressMode)
+	handleInterrupts()
+
+	printInfo("TimeIt by Tony Redondo\n\n")
+	cfg, err := loadConfiguration(options.configurationFilePath)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
@@ -68,28 +107,129 @@ func main() {
 	cfg.JsonExporterFilePath = replaceCustomVars(cfg.JsonExporterFilePath)
 	exporters = append(exporters, newDatadogExporter(), newJsonExporter())
 
-	fmt.Printf("Warmup count: %v\n", cfg.WarmUpCount)
-	fmt.Printf("Count: %v\n", cfg.Count)
-	fmt.Printf("Number of scenarios: %v\n\n", len(cfg.Scenarios))
+	executionOrder := cfg.ExecutionOrder
+	if executionOrder == "" {
+		executionOrder = executionOrderSequential
+	}
+	var seed *int64
+	if executionOrder == executionOrderShuffled {
+		seedValue := time.Now().UnixNano()
+		if cfg.Seed != nil {
+			seedValue = *cfg.Seed
+		}
+		seed = &seedValue
+	}
+
+	printInfo("Warmup count: %v\n", cfg.WarmUpCount)
+	if cfg.WarmUpDuration > 0 {
+		printInfo("Warmup duration: %vs\n", cfg.WarmUpDuration)
+	}
+	if cfg.Adaptive != nil {
+		printInfo("Count: adaptive (%v <= %v, min: %v, max: %v)\n",
+			cfg.Adaptive.Criterion, cfg.Adaptive.Target, cfg.Adaptive.MinCount, cfg.Adaptive.MaxCount)
+	} else {
+		printInfo("Count: %v\n", cfg.Count)
+	}
+	if cfg.MinRunTime > 0 {
+		printInfo("Min run time: %vs\n", cfg.MinRunTime)
+	}
+	if cfg.MaxRunTime > 0 {
+		printInfo("Max run time: %vs\n", cfg.MaxRunTime)
+	}
+	if cfg.MaxTotalRunTime > 0 {
+		printInfo("Max total run time: %vs\n", cfg.MaxTotalRunTime)
+	}
+	if cfg.Calibration != nil {
+		printInfo("Calibration: %v (count: %v, subtract: %v)\n",
+			*cfg.Calibration.ProcessName, cfg.Calibration.Count, cfg.Calibration.Subtract)
+	}
+	printInfo("Confidence intervals: %v%% (bootstrap resamples: %v, seed: %v)\n",
+		cfg.ConfidenceIntervals.Level*100, cfg.ConfidenceIntervals.Resamples, *cfg.ConfidenceIntervals.Seed)
+	if seed != nil {
+		printInfo("Execution order: %v (seed: %v)\n", executionOrder, *seed)
+	} else {
+		printInfo("Execution order: %v\n", executionOrder)
+	}
+	if cfg.Baseline != "" {
+		printInfo("Baseline: %v\n", cfg.Baseline)
+	}
+	printInfo("Number of scenarios: %v\n\n", len(cfg.Scenarios))
 
 	// process each scenario
 	var resScenario []scenarioResult
 	scenarioWithErrors := 0
-	if cfg.Count > 0 && len(cfg.Scenarios) > 0 {
-		for _, sce := range cfg.Scenarios {
-			// Prepare scenario
+	if cfg.hasIterations() {
+		// Prepare scenarios
+		scenarios := make([]scenario, len(cfg.Scenarios))
+		for idx, sce := range cfg.Scenarios {
 			prepareScenario(&sce, cfg)
+			scenarios[idx] = sce
+		}
 
-			// Process scenario
-			res := processScenario(&sce, cfg)
-			if res.Error != nil {
+		if !schedulingSupported {
+			for _, sce := range scenarios {
+				if len(sce.CPUAffinity) > 0 || sce.Niceness != nil {
+					fmt.Print("Warning: cpuAffinity and niceness are only supported on Linux and will be ignored.\n\n")
+					break
+				}
+			}
+		}
+		printPerfCountersWarnings(scenarios)
+		if !samplingSupported {
+			for _, sce := range scenarios {
+				if sce.Sampling != nil {
+					fmt.Print("Warning: sampling is only supported on Linux and will be ignored.\n\n")
+					break
+				}
+			}
+		}
+
+		// The deadline covers all the work of the configuration, including the setup and teardown
+		if cfg.MaxTotalRunTime > 0 {
+			deadline = time.Now().Add(time.Duration(cfg.MaxTotalRunTime) * time.Second)
+		}
+
+		// Run the configuration setup commands
+		if err := runConfigCommands(cfg.Setup, cfg); err != nil {
+			fmt.Printf("Error in setup: %v\n", err)
+			os.Exit(1)
+			return
+		}
+
+		// Process scenarios
+		if executionOrder == executionOrderSequential {
+			for idx := range scenarios {
+				res := processScenario(&scenarios[idx], cfg)
+				resScenario = append(resScenario, res)
+				if res.StopReason == stopReasonFailFast {
+					fmt.Print("Stopping the benchmark due to failFast.\n\n")
+					break
+				}
+			}
+		} else {
+			var rnd *rand.Rand
+			if seed != nil {
+				rnd = rand.New(rand.NewSource(*seed))
+			}
+			resScenario = processScenariosInterleaved(scenarios, cfg, executionOrder, rnd)
+		}
+
+		// Run the configuration teardown commands
+		if err := runConfigCommands(cfg.Teardown, cfg); err != nil {
+			fmt.Printf("Error in teardown: %v\n", err)
+		}
+
+		for idx := range resScenario {
+			resScenario[idx].ExecutionOrder = executionOrder
+			resScenario[idx].Seed = seed
+			if resScenario[idx].Failed {
 				scenarioWithErrors++
 			}
-			resScenario = append(resScenario, res)
 		}
+		compareWithBaseline(resScenario, cfg)
 	}
 
-	if scenarioWithErrors < len(cfg.Scenarios) {
+	if scenarioWithErrors < len(resScenario) || len(resScenario) == 0 {
 		// print results in a table
 		printResultsTable(resScenario, cfg)
 
@@ -101,6 +241,17 @@ func main() {
 			}
 		}
 
+		// exit with a different code when only some scenarios failed
+		if scenarioWithErrors > 0 {
+			for scidx := 0; scidx < len(resScenario); scidx++ {
+				if resScenario[scidx].Failed {
+					fmt.Printf("Scenario '%v' failed:\n", resScenario[scidx].Name)
+					fmt.Println(resScenario[scidx].Error.Error())
+				}
+			}
+			os.Exit(2)
+		}
+
 	} else {
 		for scidx := 0; scidx < len(resScenario); scidx++ {
 			if resScenario[scidx].Error != nil {
@@ -122,11 +273,36 @@ func prepareScenario(sce *scenario, cfg *config) {
 		*sce.ProcessName = replaceCustomVars(*sce.ProcessName)
 	}
 
-	if (sce.ProcessArguments == nil || *sce.ProcessArguments == "") && cfg.ProcessArguments != nil {
+	if sce.ProcessArguments.IsEmpty() && cfg.ProcessArguments != nil {
 		sce.ProcessArguments = cfg.ProcessArguments
 	}
-	if sce.ProcessArguments != nil {
-		*sce.ProcessArguments = replaceCustomVars(*sce.ProcessArguments)
+	sce.ProcessArguments = sce.ProcessArguments.Replace(replaceCustomVars)
+
+	if sce.WarmUpCount == nil {
+		sce.WarmUpCount = &cfg.WarmUpCount
+	}
+	if sce.WarmUpDuration == nil {
+		sce.WarmUpDuration = &cfg.WarmUpDuration
+	}
+	if sce.AutoWarmUp == nil {
+		sce.AutoWarmUp = cfg.AutoWarmUp
+	}
+	// a scenario with its own count doesn't use the adaptive settings of the configuration
+	if sce.Adaptive == nil && sce.Count == nil {
+		sce.Adaptive = cfg.Adaptive
+	}
+	if sce.Count == nil {
+		sce.Count = &cfg.Count
+	}
+	if sce.MaxRunTime == nil {
+		sce.MaxRunTime = &cfg.MaxRunTime
+	}
+	if sce.MinRunTime == nil {
+		sce.MinRunTime = &cfg.MinRunTime
+	}
+
+	if sce.Shell == nil && cfg.Shell != nil {
+		sce.Shell = cfg.Shell
 	}
 
 	if (sce.WorkingDirectory == nil || *sce.WorkingDirectory == "") && cfg.WorkingDirectory != nil {
@@ -136,6 +312,9 @@ func prepareScenario(sce *scenario, cfg *config) {
 		*sce.WorkingDirectory = replaceCustomVars(*sce.WorkingDirectory)
 	}
 
+	if sce.EnvironmentVariables == nil {
+		sce.EnvironmentVariables = map[string]string{}
+	}
 	for k, v := range sce.EnvironmentVariables {
 		sce.EnvironmentVariables[k] = replaceCustomVars(v)
 	}
@@ -146,6 +325,45 @@ func prepareScenario(sce *scenario, cfg *config) {
 			sce.EnvironmentVariables[k] = v
 		}
 	}
+	if sce.InheritEnvironment == nil {
+		sce.InheritEnvironment = cfg.InheritEnvironment
+	}
+	sce.PassEnvironmentVariables = append(append([]string{}, cfg.PassEnvironmentVariables...), sce.PassEnvironmentVariables...)
+	sce.UnsetEnvironmentVariables = append(append([]string{}, cfg.UnsetEnvironmentVariables...), sce.UnsetEnvironmentVariables...)
+
+	if len(sce.ExpectedExitCode) == 0 {
+		sce.ExpectedExitCode = cfg.ExpectedExitCode
+	}
+	if sce.Stdout == nil {
+		sce.Stdout = cfg.Stdout
+	}
+	if sce.Stderr == nil {
+		sce.Stderr = cfg.Stderr
+	}
+	if sce.Readiness == nil {
+		sce.Readiness = cfg.Readiness
+	}
+
+	if sce.Service == nil {
+		sce.Service = cfg.Service
+	}
+	if sce.Service != nil {
+		sceService := *sce.Service
+		processName := replaceCustomVars(*sceService.ProcessName)
+		sceService.ProcessName = &processName
+		sceService.ProcessArguments = sceService.ProcessArguments.Replace(replaceCustomVars)
+		sceService.Ready.TCP = replaceCustomVars(sceService.Ready.TCP)
+		sceService.Ready.HTTP = replaceCustomVars(sceService.Ready.HTTP)
+		sce.Service = &sceService
+	}
+	if sce.HTTPProbe == nil && cfg.HTTPProbe != nil {
+		// each scenario uses its own http client
+		sceProbe := *cfg.HTTPProbe
+		sce.HTTPProbe = &sceProbe
+	}
+	if sce.HTTPProbe != nil {
+		sce.HTTPProbe.URL = replaceCustomVars(sce.HTTPProbe.URL)
+	}
 
 	if sce.Timeout.MaxDuration <= 0 && cfg.Timeout.MaxDuration > 0 {
 		sce.Timeout.MaxDuration = cfg.Timeout.MaxDuration
@@ -158,11 +376,79 @@ func prepareScenario(sce *scenario, cfg *config) {
 		*sce.Timeout.ProcessName = replaceCustomVars(*sce.Timeout.ProcessName)
 	}
 
-	if (sce.Timeout.ProcessArguments == nil || *sce.Timeout.ProcessArguments == "") && cfg.Timeout.ProcessArguments != nil {
+	if sce.Timeout.ProcessArguments.IsEmpty() && cfg.Timeout.ProcessArguments != nil {
 		sce.Timeout.ProcessArguments = cfg.Timeout.ProcessArguments
 	}
-	if sce.Timeout.ProcessArguments != nil {
-		*sce.Timeout.ProcessArguments = replaceCustomVars(*sce.Timeout.ProcessArguments)
+	sce.Timeout.ProcessArguments = sce.Timeout.ProcessArguments.Replace(replaceCustomVars)
+
+	if sce.Timeout.Shell == nil && cfg.Timeout.Shell != nil {
+		sce.Timeout.Shell = cfg.Timeout.Shell
+	}
+
+	if sce.Timeout.GracePeriod <= 0 && cfg.Timeout.GracePeriod > 0 {
+		sce.Timeout.GracePeriod = cfg.Timeout.GracePeriod
+	}
+
+	if len(sce.BeforeEach) == 0 {
+		sce.BeforeEach = cfg.BeforeEach
+	}
+	sce.BeforeEach = prepareCommands(sce.BeforeEach)
+
+	if len(sce.AfterEach) == 0 {
+		sce.AfterEach = cfg.AfterEach
+	}
+	sce.AfterEach = prepareCommands(sce.AfterEach)
+
+	// setup and teardown commands at configuration level are not inherited, they run once for all scenarios
+	sce.Setup = prepareCommands(sce.Setup)
+	sce.Teardown = prepareCommands(sce.Teardown)
+
+	// stdin and stdinFile are replaced together, so a scenario can't inherit the other one
+	if sce.Stdin == nil && (sce.StdinFile == nil || *sce.StdinFile == "") {
+		sce.Stdin = cfg.Stdin
+		sce.StdinFile = cfg.StdinFile
+	}
+	if sce.StdinFile != nil {
+		stdinFile := replaceCustomVars(*sce.StdinFile)
+		sce.StdinFile = &stdinFile
+	}
+
+	if sce.FailFast == nil {
+		sce.FailFast = cfg.FailFast
+	}
+	if sce.MaxFailures == nil {
+		sce.MaxFailures = cfg.MaxFailures
+	}
+	if sce.MaxFailureRatio == nil {
+		sce.MaxFailureRatio = cfg.MaxFailureRatio
+	}
+	if sce.Retries <= 0 && cfg.Retries > 0 {
+		sce.Retries = cfg.Retries
+	}
+
+	if len(sce.CPUAffinity) == 0 {
+		sce.CPUAffinity = cfg.CPUAffinity
+	}
+	if sce.Niceness == nil {
+		sce.Niceness = cfg.Niceness
+	}
+	if sce.Sampling == nil {
+		sce.Sampling = cfg.Sampling
+	}
+	if sce.OutlierPolicy == nil {
+		sce.OutlierPolicy = cfg.OutlierPolicy
+	}
+	if len(sce.PerfCounters) == 0 {
+		sce.PerfCounters = cfg.PerfCounters
+	}
+
+	if sce.Artifacts == nil && cfg.Artifacts != nil {
+		sce.Artifacts = cfg.Artifacts
+	}
+	if sce.Artifacts != nil {
+		sceArtifacts := *sce.Artifacts
+		sceArtifacts.Path = replaceCustomVars(sceArtifacts.Path)
+		sce.Artifacts = &sceArtifacts
 	}
 
 	if (sce.MetricsFilePath == nil || *sce.MetricsFilePath == "") && cfg.MetricsFilePath != nil {
@@ -174,72 +460,195 @@ func prepareScenario(sce *scenario, cfg *config) {
 }
 
 func processScenario(scenario *scenario, cfg *config) scenarioResult {
-	fmt.Printf("Scenario: %v\n", scenario.Name)
-	fmt.Print("  Warming up")
+	printInfo("Scenario: %v\n", scenario.Name)
+	if deadlineExceeded() {
+		fmt.Printf("  Skipped: maxTotalRunTime exhausted\n\n")
+		return getScenarioErrorResult(scenario, errDeadlineExceeded)
+	}
+	if err := runScenarioCommands(scenario.Setup, scenario); err != nil {
+		fmt.Printf("  Error in setup: %v\n\n", err)
+		return getScenarioErrorResult(scenario, err)
+	}
+	var svc *runningService
+	var serviceStartupTime *time.Duration
+	if scenario.Service != nil {
+		printInfo("  Starting service\n")
+		runningSvc, startupTime, err := startService(scenario)
+		if err != nil {
+			fmt.Printf("  Error starting the service: %v\n\n", err)
+			return addScenarioError(getScenarioErrorResult(scenario, err), runScenarioCommands(scenario.Teardown, scenario))
+		}
+		printInfo("    Ready in: %v\n", startupTime)
+		svc = runningSvc
+		serviceStartupTime = &startupTime
+	}
+	var calibration *calibrationResult
+	if cfg.Calibration != nil && scenario.HTTPProbe == nil {
+		var err error
+		if calibration, err = calibrateScenario(scenario, cfg, "  Calibrating"); err != nil {
+			fmt.Printf("  Error in calibration: %v\n\n", err)
+			if svc != nil {
+				svc.stop()
+			}
+			return addScenarioError(getScenarioErrorResult(scenario, err), runScenarioCommands(scenario.Teardown, scenario))
+		}
+		printInfo("    Baseline: %v\n", calibration)
+	}
 	start := time.Now()
-	_ = runScenario(cfg.WarmUpCount, scenario)
+	warmUp := newWarmUpRun(scenario)
+	runScenario(warmUp, "  Warming up")
 	end := time.Now()
-	fmt.Printf("    Duration: %v\n", end.Sub(start))
-	fmt.Print("  Run")
+	printInfo("    Duration: %v\n", end.Sub(start))
+	if warmUp.autoWarmUp != nil {
+		printInfo("    Stop reason: %v (%v iterations)\n", warmUp.stopReason, len(warmUp.data))
+	}
 	start = time.Now()
-	res := runScenario(cfg.Count, scenario)
+	run := newScenarioRun(scenario)
+	run.calibration = calibration
+	runScenario(run, "  Run")
 	end = time.Now()
-	fmt.Printf("    Duration: %v\n", end.Sub(start))
-	fmt.Println()
+	printInfo("    Duration: %v\n", end.Sub(start))
+	if run.stopReason != stopReasonCount {
+		printInfo("    Stop reason: %v\n", run.stopReason)
+	}
+	if svc != nil {
+		svc.stop()
+	}
+	teardownErr := runScenarioCommands(scenario.Teardown, scenario)
+	if teardownErr != nil {
+		fmt.Printf("  Error in teardown: %v\n", teardownErr)
+	}
+	printInfo("\n")
+
+	result := getScenarioResult(run)
+	result.ServiceStartupTime = serviceStartupTime
+	result.setWarmUp(warmUp)
+	result.setConfidenceIntervals(cfg.ConfidenceIntervals)
+	return addScenarioError(result, teardownErr)
+}
+
+// getScenarioErrorResult returns the result of a scenario that couldn't be executed
+func getScenarioErrorResult(scenario *scenario, err error) scenarioResult {
+	return scenarioResult{
+		scenario:          *scenario,
+		scenarioDataPoint: scenarioDataPoint{Error: err},
+		Environment:       getEffectiveEnvironment(&scenario.processData),
+		Failed:            true,
+		StopReason:        stopReasonError,
+	}
+}
+
+// addScenarioError appends an error to the scenario result error
+func addScenarioError(result scenarioResult, err error) scenarioResult {
+	if err == nil {
+		return result
+	}
+	if result.Error != nil {
+		result.Error = errors.New(fmt.Sprintf("%s%s", result.Error.Error(), err.Error()))
+	} else {
+		result.Error = err
+	}
+	result.Failed = true
+	return result
+}
+
+// setWarmUp records the warmup iterations executed before the scenario run
+func (r *scenarioResult) setWarmUp(warmUp *scenarioRun) {
+	r.WarmUpCount = len(warmUp.data)
+	for _, item := range warmUp.data {
+		r.WarmUpDurations = append(r.WarmUpDurations, float64(item.Duration))
+	}
+	if warmUp.autoWarmUp != nil {
+		r.WarmUpStopReason = warmUp.stopReason
+	}
+}
 
+// getScenarioResult calculates the statistics of a scenario run
+func getScenarioResult(run *scenarioRun) scenarioResult {
+	res := run.data
 	var durations []float64
-	metricsData := map[string][]float64{}
+	var durationIndexes []int
 	mapErrors := make(map[string]bool)
-	for _, item := range res {
-		durations = append(durations, float64(item.Duration))
-		if item.Error != nil && item.Error != context.DeadlineExceeded {
-			mapErrors[item.Error.Error()] = true
+	timeouts := 0
+	failures := 0
+	for idx, item := range res {
+		// timed out iterations are killed, so their duration is not a measurement
+		if item.Status == dataPointStatusTimeout {
+			timeouts++
+			continue
 		}
-		for k, v := range item.metrics {
-			metricsData[k] = append(metricsData[k], v)
+		// failed iterations are excluded from the statistics
+		if item.Error != nil {
+			failures++
+			mapErrors[item.Error.Error()] = true
+			continue
 		}
+		durations = append(durations, float64(item.Duration))
+		durationIndexes = append(durationIndexes, idx)
 	}
 	var errorString string
 	for k := range mapErrors {
 		errorString += fmt.Sprintln(k)
 	}
+	policyErr := getFailurePolicyError(run.scenario, failures+timeouts, len(res), run.stopReason)
+	if policyErr != nil {
+		errorString += fmt.Sprintln(policyErr.Error())
+	}
 	var sceError error
 	if errorString != "" {
 		sceError = errors.New(errorString)
 	}
 
-	// Get outliers
-	outliers, _ := stats.QuartileOutliers(durations)
-	extremeOutliers := outliers.Extreme
-
-	durationsCount := len(durations)
-	outliersCount := len(extremeOutliers)
-
-	// Remove outliers
+	// Remove the outliers, the iterations are removed from both the durations and the metrics
+	outlierPolicy := run.scenario.OutlierPolicy
+	if outlierPolicy == nil {
+		outlierPolicy = &defaultOutlierPolicy
+	}
+	outlierPositions := outlierPolicy.outlierIndexes(durations)
 	var newDurations []float64
-	for x := 0; x < durationsCount; x++ {
-		add := true
-		for j := 0; j < outliersCount; j++ {
-			if durations[x] == extremeOutliers[j] {
-				add = false
-				break
-			}
+	var outliers []float64
+	var outlierIndexes []int
+	metricsData := map[string][]float64{}
+	for pos, duration := range durations {
+		if outlierPositions[pos] {
+			outliers = append(outliers, duration)
+			outlierIndexes = append(outlierIndexes, durationIndexes[pos])
+			continue
 		}
-		if add {
-			newDurations = append(newDurations, durations[x])
+		newDurations = append(newDurations, duration)
+		for k, v := range res[durationIndexes[pos]].metrics {
+			metricsData[k] = append(metricsData[k], v)
 		}
 	}
 
-	// Calculate stats
+	// Subtract the calibration baseline, the outliers are shifted too so they keep the scale of the durations
 	durations = newDurations
-	mean, _ := stats.Mean(durations)
-	max, _ := stats.Max(durations)
-	min, _ := stats.Min(durations)
-	stdev, _ := stats.StandardDeviation(durations)
-	p99, _ := stats.Percentile(durations, 99)
-	p95, _ := stats.Percentile(durations, 95)
-	p90, _ := stats.Percentile(durations, 90)
-	stderr := stdev / math.Sqrt(float64(durationsCount))
+	if run.calibration != nil && run.calibration.Subtracted {
+		for idx := range durations {
+			durations[idx] -= run.calibration.Mean
+		}
+		for idx := range outliers {
+			outliers[idx] -= run.calibration.Mean
+		}
+	}
+
+	// Calculate stats
+	var mean, median, max, min, stdev, p99, p95, p90, stderr float64
+	if len(durations) > 0 {
+		mean, _ = stats.Mean(durations)
+		median, _ = stats.Median(durations)
+		max, _ = stats.Max(durations)
+		min, _ = stats.Min(durations)
+		stdev, _ = stats.StandardDeviation(durations)
+		p99, _ = stats.Percentile(durations, 99)
+		p95, _ = stats.Percentile(durations, 95)
+		p90, _ = stats.Percentile(durations, 90)
+		stderr = stdev / math.Sqrt(float64(len(durations)))
+		if run.calibration != nil && run.calibration.Subtracted {
+			// the uncertainty of the baseline is propagated to the standard error of the mean
+			stderr = math.Sqrt(stderr*stderr + run.calibration.StdErr*run.calibration.StdErr)
+		}
+	}
 
 	// Calculate metrics stats
 	metricsStats := map[string]float64{}
@@ -248,7 +657,7 @@ func processScenario(scenario *scenario, cfg *config) scenarioResult {
 		mMax, _ := stats.Max(v)
 		mMin, _ := stats.Min(v)
 		mStdDev, _ := stats.StandardDeviation(v)
-		mStdErr := mStdDev / math.Sqrt(float64(durationsCount))
+		mStdErr := mStdDev / math.Sqrt(float64(len(v)))
 		mP99, _ := stats.Percentile(v, 99)
 		mP95, _ := stats.Percentile(v, 95)
 		mP90, _ := stats.Percentile(v, 90)
@@ -263,66 +672,57 @@ func processScenario(scenario *scenario, cfg *config) scenarioResult {
 		metricsStats[fmt.Sprintf("%v.p90", k)] = mP90
 	}
 
+	var precision *float64
+	if run.adaptive != nil && !math.IsInf(run.precision, 0) {
+		precision = &run.precision
+	}
+
 	return scenarioResult{
-		scenario: *scenario,
+		scenario: *run.scenario,
 		scenarioDataPoint: scenarioDataPoint{
-			Start:    start,
-			End:      end,
-			Duration: end.Sub(start),
+			Start:    run.start,
+			End:      run.end,
+			Duration: run.end.Sub(run.start),
 			Error:    sceError,
 		},
-		WarmUpCount: cfg.WarmUpCount,
-		Count:       cfg.Count,
-		Data:        res,
-		DataFloat:   durations,
-		Outliers:    extremeOutliers,
-		Mean:        mean,
-		Max:         max,
-		Min:         min,
-		Stdev:       stdev,
-		StdErr:      stderr,
-		P99:         p99,
-		P95:         p95,
-		P90:         p90,
-		Metrics:     metricsStats,
-		MetricsData: metricsData,
+		Count:          len(res),
+		Scheduling:     getSchedulingSettings(run.scenario),
+		Failed:         policyErr != nil,
+		Timeouts:       timeouts,
+		Failures:       failures,
+		Retries:        run.retries,
+		StopReason:     run.stopReason,
+		Precision:      precision,
+		Calibration:    run.calibration,
+		Environment:    getEffectiveEnvironment(&run.scenario.processData),
+		Data:           res,
+		DataFloat:      durations,
+		Outliers:       outliers,
+		OutlierIndexes: outlierIndexes,
+		OutlierPolicy:  outlierPolicy.String(),
+		Mean:           mean,
+		Median:         median,
+		Max:            max,
+		Min:            min,
+		Stdev:          stdev,
+		StdErr:         stderr,
+		P99:            p99,
+		P95:            p95,
+		P90:            p90,
+		Metrics:        metricsStats,
+		MetricsData:    metricsData,
 	}
 }
 
-func runScenario(count int, scenario *scenario) []scenarioDataPoint {
-	var res []scenarioDataPoint
-	fmt.Print(" ")
-	for i := 0; i < count; i++ {
-		currentRun := runProcessCmd(scenario)
-		res = append(res, currentRun)
-		if !currentRun.shouldContinue {
-			break
-		}
-		if currentRun.Error != nil {
-			fmt.Print("x")
-		} else {
-			fmt.Print(".")
-		}
-	}
-	fmt.Println()
-	return res
-}
-
 func runProcessCmd(sce *scenario) scenarioDataPoint {
 	var cmdString string
-	var cmdArguments string
 	var workingDirectory string
 	var timeoutCmdString string
-	var timeoutCmdArguments string
 
 	if sce.ProcessName != nil {
 		cmdString = *sce.ProcessName
 	}
 
-	if sce.ProcessArguments != nil {
-		cmdArguments = *sce.ProcessArguments
-	}
-
 	if sce.WorkingDirectory != nil {
 		workingDirectory = *sce.WorkingDirectory
 	}
@@ -333,70 +733,135 @@ func runProcessCmd(sce *scenario) scenarioDataPoint {
 		timeoutCmdString = *sce.Timeout.ProcessName
 	}
 
-	if sce.Timeout.ProcessArguments != nil {
-		timeoutCmdArguments = *sce.Timeout.ProcessArguments
-	}
-
-	cmdEnv := os.Environ()
-	for k, v := range sce.EnvironmentVariables {
-		cmdEnv = append(cmdEnv, fmt.Sprintf("%s=%s", k, v))
-	}
+	cmdEnv := buildEnvironment(&sce.processData)
 
 	defer runtime.GC()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	var cmd *exec.Cmd
-	if len(cmdArguments) > 0 {
-		cmd = exec.CommandContext(ctx, cmdString, strings.Split(cmdArguments, " ")...)
-	} else {
-		cmd = exec.CommandContext(ctx, cmdString)
-	}
+	cmd := newCommand(context.Background(), cmdString, sce.ProcessArguments, sce.Shell != nil && *sce.Shell)
 	cmd.Dir = workingDirectory
 	cmd.Env = cmdEnv
+	setProcessGroup(cmd)
 
-	if cmdTimeout > 0 {
-		go func() {
-			select {
-			case <-time.After(time.Duration(cmdTimeout) * time.Second):
-				if timeoutCmdString != "" {
-					fmt.Printf("[%v]", cmd.Process.Pid)
-					timeoutCmdString = strings.ReplaceAll(timeoutCmdString, "%pid%", fmt.Sprint(cmd.Process.Pid))
-					timeoutCmdArguments = strings.ReplaceAll(timeoutCmdArguments, "%pid%", fmt.Sprint(cmd.Process.Pid))
-					var timeoutCmd *exec.Cmd
-					if len(timeoutCmdArguments) > 0 {
-						timeoutCmd = exec.CommandContext(ctx, timeoutCmdString, strings.Split(timeoutCmdArguments, " ")...)
-					} else {
-						timeoutCmd = exec.CommandContext(ctx, timeoutCmdString)
-					}
-					err := timeoutCmd.Run()
-					if err != nil {
-						fmt.Println(err)
-					}
+	// stdin is opened again on each iteration, so every process reads the whole content
+	stdin, closeStdin, err := openStdin(sce, workingDirectory)
+	if err != nil {
+		now := time.Now()
+		return scenarioDataPoint{
+			Start:          now,
+			End:            now,
+			Status:         dataPointStatusError,
+			Error:          errors.New(fmt.Sprintf("\nError opening stdin: %s", err.Error())),
+			shouldContinue: false,
+		}
+	}
+	defer closeStdin()
+	cmd.Stdin = stdin
+
+	// the timeout goroutine kills the whole process group, so grandchildren don't survive the iteration,
+	// it's started once the process is running so the clock doesn't count the setup before exec
+	processDone := make(chan struct{})
+	timeoutDone := make(chan struct{})
+	timedOut := false
+	watchTimeout := func(pid int) {
+		defer close(timeoutDone)
+		select {
+		case <-time.After(time.Duration(cmdTimeout) * time.Second):
+			timedOut = true
+			if timeoutCmdString != "" {
+				progress.message(fmt.Sprintf("timeout, running the timeout command for pid %v", pid))
+				replacePid := func(value string) string {
+					return strings.ReplaceAll(value, "%pid%", fmt.Sprint(pid))
+				}
+				timeoutCmdString = replacePid(timeoutCmdString)
+				timeoutCmdArguments := sce.Timeout.ProcessArguments.Replace(replacePid)
+				timeoutCmd := newCommand(context.Background(), timeoutCmdString, timeoutCmdArguments, sce.Timeout.Shell != nil && *sce.Timeout.Shell)
+				err := timeoutCmd.Run()
+				if err != nil {
+					progress.message(fmt.Sprintf("error running the timeout command: %v", err))
+				}
+			}
+			if sce.Timeout.GracePeriod > 0 {
+				_ = terminateProcessGroup(cmd)
+				select {
+				case <-time.After(time.Duration(sce.Timeout.GracePeriod) * time.Second):
+				case <-processDone:
+					return
 				}
-				cancel()
-			case <-ctx.Done():
-				cancel()
 			}
-		}()
+			_ = killProcessGroup(cmd)
+		case <-processDone:
+		}
 	}
 
-	var b bytes.Buffer
-	cmd.Stdout = &b
-	cmd.Stderr = &b
+	// the combined output is used for error messages, stdout and stderr are also kept apart for the assertions
+	var b syncBuffer
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	var watcher *outputWatcher
+	if sce.Readiness != nil {
+		watcher = newOutputWatcher(sce.Readiness, func() {
+			_ = killProcessGroup(cmd)
+		})
+	}
+	cmd.Stdout = watcher.wrap(readinessStreamStdout, &stdout, &b)
+	cmd.Stderr = watcher.wrap(readinessStreamStderr, &stderr, &b)
 
 	shouldContinue := true
 	start := time.Now()
 	startDur := hrtime.Now()
-	err := cmd.Run()
+	var sampler *processSampler
+	counters, err := startProcess(cmd, sce, sce.PerfCounters)
+	if err == nil {
+		trackProcess(cmd)
+		if cmdTimeout > 0 {
+			go watchTimeout(cmd.Process.Pid)
+		} else {
+			close(timeoutDone)
+		}
+		sampler = startSampling(sce.Sampling, cmd.Process.Pid)
+		err = cmd.Wait()
+		untrackProcess(cmd)
+	} else {
+		close(timeoutDone)
+	}
 	endDur := hrtime.Now()
 	end := time.Now()
+	close(processDone)
+	<-timeoutDone
+	sampler.stop()
+	// the inherited counters are collected once the process has been waited
+	perfCounterValues := counters.read()
+	counters.close()
+
+	var exitCode *int
+	if cmd.ProcessState != nil {
+		code := cmd.ProcessState.ExitCode()
+		exitCode = &code
+	}
 
-	if ctx.Err() == context.DeadlineExceeded {
-		err = ctx.Err()
-	} else if err != nil {
+	var timeToFirstOutput, timeToReady *time.Duration
+	if watcher != nil {
+		timeToFirstOutput, timeToReady = watcher.results(startDur)
+	}
+	// the exit code is not checked if the process was terminated once ready
+	terminatedOnReady := timeToReady != nil && sce.Readiness.Terminate
+
+	status := dataPointStatusSuccess
+	if timedOut {
+		status = dataPointStatusTimeout
+		err = errors.New(fmt.Sprintf("\n%sprocess timed out after %vs", b.String(), cmdTimeout))
+	} else if exitCode == nil {
+		status = dataPointStatusError
 		err = errors.New(fmt.Sprintf("\n%s%s", b.String(), err.Error()))
+	} else if sce.Readiness != nil && sce.Readiness.pattern != nil && timeToReady == nil {
+		status = dataPointStatusFailed
+		err = errors.New(fmt.Sprintf("\n%sreadiness pattern '%s' not found", b.String(), sce.Readiness.Pattern))
+	} else if aErr := checkAssertions(sce, *exitCode, !terminatedOnReady, stdout.Bytes(), stderr.Bytes()); aErr != nil {
+		status = dataPointStatusFailed
+		err = errors.New(fmt.Sprintf("\n%s%s", b.String(), aErr.Error()))
+	} else {
+		// the exit code was expected
+		err = nil
 	}
 
 	// Since metrics file(s) are created during or at the end of the process
@@ -406,7 +871,18 @@ func runProcessCmd(sce *scenario) scenarioDataPoint {
 		metricsFilesPath = resolveWildcard(*sce.MetricsFilePath, workingDirectory)
 	}
 
+	usage := getResourceUsage(cmd.ProcessState)
+
 	metricsData := map[string]float64{}
+	usage.addMetrics(metricsData)
+	sampler.addMetrics(metricsData)
+	addPerfCounterMetrics(perfCounterValues, metricsData)
+	if timeToFirstOutput != nil {
+		metricsData["process.time_to_first_output_ms"] = float64(*timeToFirstOutput) / float64(time.Millisecond)
+	}
+	if timeToReady != nil {
+		metricsData["process.time_to_ready_ms"] = float64(*timeToReady) / float64(time.Millisecond)
+	}
 	if len(metricsFilesPath) > 0 {
 		for _, metricsFilePath := range metricsFilesPath {
 			if _, lerr := os.Stat(metricsFilePath); lerr == nil {
@@ -425,18 +901,27 @@ func runProcessCmd(sce *scenario) scenarioDataPoint {
 				}
 			} else if os.IsNotExist(lerr) {
 				err = errors.New(fmt.Sprintf("MetricsFilePath '%v' not found.", metricsFilePath))
+				status = dataPointStatusError
 				shouldContinue = false
 			}
 		}
 	}
 
 	return scenarioDataPoint{
-		Start:          start,
-		End:            end,
-		Duration:       endDur - startDur,
-		Error:          err,
-		metrics:        metricsData,
-		shouldContinue: shouldContinue,
+		Start:             start,
+		End:               end,
+		Duration:          endDur - startDur,
+		Status:            status,
+		ExitCode:          exitCode,
+		Error:             err,
+		ResourceUsage:     usage,
+		PerfCounters:      perfCounterValues,
+		TimeToFirstOutput: timeToFirstOutput,
+		TimeToReady:       timeToReady,
+		metrics:           metricsData,
+		stdout:            stdout.Bytes(),
+		stderr:            stderr.Bytes(),
+		shouldContinue:    shouldContinue,
 	}
 }
 
@@ -452,7 +937,14 @@ func printResultsTable(resScenario []scenarioResult, cfg *config) {
 		resultHeader = append(resultHeader, resScenario[scidx].Name)
 	}
 	resultTable.SetHeader(resultHeader)
-	for idx := 0; idx < cfg.Count; idx++ {
+	maxResultsLength := 0
+	for scidx := 0; scidx < len(resScenario); scidx++ {
+		resLength := len(resScenario[scidx].DataFloat)
+		if maxResultsLength < resLength {
+			maxResultsLength = resLength
+		}
+	}
+	for idx := 0; idx < maxResultsLength; idx++ {
 		var resultRow []string
 		for scidx := 0; scidx < len(resScenario); scidx++ {
 			cScenario := resScenario[scidx]
@@ -498,25 +990,48 @@ func printResultsTable(resScenario []scenarioResult, cfg *config) {
 	summaryTable := tablewriter.NewWriter(os.Stdout)
 	summaryTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
 	summaryTable.SetCenterSeparator("|")
-	summaryTable.SetHeader([]string{"Name", "Mean", "StdDev", "StdErr", "P99", "P95", "P90", "Outliers"})
+	summaryTable.SetHeader([]string{"Name", "Mean", "Median", "StdDev", "StdErr", "P99", "P95", "P90", "Outliers", "Timeouts", "Failures"})
 	for scidx := 0; scidx < len(resScenario); scidx++ {
 		summaryTable.Append([]string{
 			resScenario[scidx].Name,
 			fmt.Sprint(time.Duration(resScenario[scidx].Mean)),
+			fmt.Sprint(time.Duration(resScenario[scidx].Median)),
 			fmt.Sprint(time.Duration(resScenario[scidx].Stdev)),
 			fmt.Sprint(time.Duration(resScenario[scidx].StdErr)),
 			fmt.Sprint(time.Duration(resScenario[scidx].P99)),
 			fmt.Sprint(time.Duration(resScenario[scidx].P95)),
 			fmt.Sprint(time.Duration(resScenario[scidx].P90)),
 			fmt.Sprint(len(resScenario[scidx].Outliers)),
+			fmt.Sprint(resScenario[scidx].Timeouts),
+			fmt.Sprint(resScenario[scidx].Failures),
 		})
+		if ci := resScenario[scidx].ConfidenceIntervals; ci != nil {
+			prefix := "└>"
+			if len(resScenario[scidx].MetricsData) > 0 {
+				prefix = "├>"
+			}
+			summaryTable.Append([]string{
+				fmt.Sprintf("%v%v%% CI", prefix, ci.Level*100),
+				ci.Mean.String(),
+				ci.Median.String(),
+				"",
+				"",
+				ci.P99.String(),
+				ci.P95.String(),
+				ci.P90.String(),
+				"",
+				"",
+				"",
+			})
+		}
 
 		totalNum := len(resScenario[scidx].MetricsData)
 		if totalNum > 0 {
 			for idx, item := range orderByKey(resScenario[scidx].MetricsData) {
 				mMean, _ := stats.Mean(item.value)
 				mStdDev, _ := stats.StandardDeviation(item.value)
-				mStdErr := mStdDev / math.Sqrt(float64(len(resScenario[scidx].DataFloat)))
+				mMedian, _ := stats.Median(item.value)
+				mStdErr := mStdDev / math.Sqrt(float64(len(item.value)))
 				mP99, _ := stats.Percentile(item.value, 99)
 				mP95, _ := stats.Percentile(item.value, 95)
 				mP90, _ := stats.Percentile(item.value, 90)
@@ -531,18 +1046,23 @@ func printResultsTable(resScenario []scenarioResult, cfg *config) {
 				summaryTable.Append([]string{
 					name,
 					fmt.Sprint(toFixed(mMean, 6)),
+					fmt.Sprint(toFixed(mMedian, 6)),
 					fmt.Sprint(toFixed(mStdDev, 6)),
 					fmt.Sprint(toFixed(mStdErr, 6)),
 					fmt.Sprint(toFixed(mP99, 6)),
 					fmt.Sprint(toFixed(mP95, 6)),
 					fmt.Sprint(toFixed(mP90, 6)),
 					"",
+					"",
+					"",
 				})
 			}
 
-			summaryTable.Append([]string{"", "", "", "", "", "", "", ""})
+			summaryTable.Append([]string{"", "", "", "", "", "", "", "", "", "", ""})
 		}
 	}
 	summaryTable.Render()
 	fmt.Println()
+
+	printComparisonTable(resScenario, cfg)
 }
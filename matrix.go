@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type (
+	// matrix generates scenarios from the Cartesian product of its axes
+	matrix struct {
+		Axes    []matrixAxis        `json:"axes"`
+		Include []map[string]string `json:"include"`
+		Exclude []map[string]string `json:"exclude"`
+		Name    string              `json:"name"`
+	}
+
+	// matrixAxis is a parameter of the matrix with all its possible values
+	matrixAxis struct {
+		Name   string        `json:"name"`
+		Values []matrixValue `json:"values"`
+	}
+
+	// matrixValue contains the settings applied to the scenarios generated with this value
+	matrixValue struct {
+		Name                 string            `json:"name"`
+		EnvironmentVariables map[string]string `json:"environmentVariables"`
+		ProcessArguments     *arguments        `json:"processArguments"`
+		WorkingDirectory     *string           `json:"workingDirectory"`
+	}
+)
+
+var matrixNamePlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+func (m *matrix) validate() error {
+	if len(m.Axes) == 0 {
+		return errors.New("matrix must have at least one axis")
+	}
+	axes := map[string]*matrixAxis{}
+	for idx := range m.Axes {
+		axis := &m.Axes[idx]
+		if axis.Name == "" {
+			return errors.New("matrix axis name is required")
+		}
+		if _, ok := axes[axis.Name]; ok {
+			return errors.New(fmt.Sprintf("duplicated matrix axis '%s'", axis.Name))
+		}
+		if len(axis.Values) == 0 {
+			return errors.New(fmt.Sprintf("matrix axis '%s' must have at least one value", axis.Name))
+		}
+		values := map[string]bool{}
+		for _, value := range axis.Values {
+			if value.Name == "" {
+				return errors.New(fmt.Sprintf("matrix axis '%s' has a value without name", axis.Name))
+			}
+			if values[value.Name] {
+				return errors.New(fmt.Sprintf("duplicated value '%s' in matrix axis '%s'", value.Name, axis.Name))
+			}
+			values[value.Name] = true
+		}
+		axes[axis.Name] = axis
+	}
+
+	for _, rule := range append(append([]map[string]string{}, m.Include...), m.Exclude...) {
+		for axisName, pattern := range rule {
+			if _, ok := axes[axisName]; !ok {
+				return errors.New(fmt.Sprintf("unknown matrix axis '%s' in include/exclude rule", axisName))
+			}
+			if err := validatePattern(pattern); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.Name == "" {
+		var placeholders []string
+		for _, axis := range m.Axes {
+			placeholders = append(placeholders, fmt.Sprintf("{%s}", axis.Name))
+		}
+		m.Name = strings.Join(placeholders, "+")
+	}
+	for _, match := range matrixNamePlaceholder.FindAllStringSubmatch(m.Name, -1) {
+		if _, ok := axes[match[1]]; !ok {
+			return errors.New(fmt.Sprintf("unknown matrix axis '%s' in the name template", match[1]))
+		}
+	}
+	return nil
+}
+
+// expand returns the scenarios of all the combinations allowed by the include and exclude rules
+func (m *matrix) expand(base *processData) []scenario {
+	var scenarios []scenario
+	combination := make([]*matrixValue, len(m.Axes))
+	var expandAxis func(axisIdx int)
+	expandAxis = func(axisIdx int) {
+		if axisIdx == len(m.Axes) {
+			if m.allows(combination) {
+				scenarios = append(scenarios, m.newScenario(base, combination))
+			}
+			return
+		}
+		for idx := range m.Axes[axisIdx].Values {
+			combination[axisIdx] = &m.Axes[axisIdx].Values[idx]
+			expandAxis(axisIdx + 1)
+		}
+	}
+	expandAxis(0)
+	return scenarios
+}
+
+// allows returns true if the combination matches an include rule (when there are any) and no exclude rule
+func (m *matrix) allows(combination []*matrixValue) bool {
+	for _, rule := range m.Exclude {
+		if m.matches(rule, combination) {
+			return false
+		}
+	}
+	if len(m.Include) == 0 {
+		return true
+	}
+	for _, rule := range m.Include {
+		if m.matches(rule, combination) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the value of every axis in the rule matches the rule pattern
+func (m *matrix) matches(rule map[string]string, combination []*matrixValue) bool {
+	for idx, axis := range m.Axes {
+		if pattern, ok := rule[axis.Name]; ok && !matchesAnyPattern(combination[idx].Name, []string{pattern}) {
+			return false
+		}
+	}
+	return true
+}
+
+// newScenario creates the scenario of a combination, the values are applied in the axes order
+func (m *matrix) newScenario(base *processData, combination []*matrixValue) scenario {
+	valueNames := map[string]string{}
+	sce := scenario{}
+	sce.EnvironmentVariables = map[string]string{}
+	sce.Tags = map[string]string{}
+	sce.ProcessArguments = base.ProcessArguments
+	for idx, value := range combination {
+		axisName := m.Axes[idx].Name
+		valueNames[axisName] = value.Name
+		sce.Tags[fmt.Sprintf("matrix.%s", axisName)] = value.Name
+		for k, v := range value.EnvironmentVariables {
+			sce.EnvironmentVariables[k] = v
+		}
+		if !value.ProcessArguments.IsEmpty() {
+			sce.ProcessArguments = sce.ProcessArguments.Append(value.ProcessArguments)
+		}
+		if value.WorkingDirectory != nil {
+			workingDirectory := *value.WorkingDirectory
+			sce.WorkingDirectory = &workingDirectory
+		}
+	}
+	sce.Name = matrixNamePlaceholder.ReplaceAllStringFunc(m.Name, func(placeholder string) string {
+		return valueNames[placeholder[1:len(placeholder)-1]]
+	})
+	return sce
+}
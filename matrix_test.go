@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMatrixExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name:     "product",
+			json:     `{"axes": [{"name": "os", "values": [{"name": "linux"}, {"name": "windows"}]}, {"name": "mode", "values": [{"name": "a"}, {"name": "b"}]}]}`,
+			expected: []string{"linux+a", "linux+b", "windows+a", "windows+b"},
+		},
+		{
+			name:     "exclude",
+			json:     `{"axes": [{"name": "os", "values": [{"name": "linux"}, {"name": "windows"}]}, {"name": "mode", "values": [{"name": "a"}, {"name": "b"}]}], "exclude": [{"os": "win*", "mode": "b"}]}`,
+			expected: []string{"linux+a", "linux+b", "windows+a"},
+		},
+		{
+			name:     "include",
+			json:     `{"axes": [{"name": "os", "values": [{"name": "linux"}, {"name": "windows"}]}, {"name": "mode", "values": [{"name": "a"}, {"name": "b"}]}], "include": [{"mode": "a"}]}`,
+			expected: []string{"linux+a", "windows+a"},
+		},
+		{
+			name:     "name template",
+			json:     `{"axes": [{"name": "os", "values": [{"name": "linux"}]}, {"name": "mode", "values": [{"name": "a"}]}], "name": "{mode} on {os}"}`,
+			expected: []string{"a on linux"},
+		},
+	}
+	for _, test := range tests {
+		var m matrix
+		if err := json.Unmarshal([]byte(test.json), &m); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := m.validate(); err != nil {
+			t.Fatalf("%s: validate() = %v", test.name, err)
+		}
+		var names []string
+		for _, sce := range m.expand(&processData{}) {
+			names = append(names, sce.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s: scenarios = %v, expected %v", test.name, names, test.expected)
+		}
+	}
+}
+
+func TestMatrixScenarioSettings(t *testing.T) {
+	var m matrix
+	err := json.Unmarshal([]byte(`{"axes": [
+		{"name": "runtime", "values": [{"name": "net6", "environmentVariables": {"RUNTIME": "6"}, "processArguments": ["--net6"]}]},
+		{"name": "mode", "values": [{"name": "fast", "environmentVariables": {"MODE": "fast"}, "processArguments": "--fast", "workingDirectory": "/tmp"}]}
+	]}`), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.validate(); err != nil {
+		t.Fatal(err)
+	}
+	var base processData
+	if err = json.Unmarshal([]byte(`{"processArguments": ["run"]}`), &base); err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := m.expand(&base)
+	if len(scenarios) != 1 {
+		t.Fatalf("scenarios = %d, expected 1", len(scenarios))
+	}
+	sce := scenarios[0]
+	if expected := []string{"run", "--net6", "--fast"}; !reflect.DeepEqual(sce.ProcessArguments.Values(), expected) {
+		t.Errorf("arguments = %q, expected %q", sce.ProcessArguments.Values(), expected)
+	}
+	if expected := map[string]string{"RUNTIME": "6", "MODE": "fast"}; !reflect.DeepEqual(sce.EnvironmentVariables, expected) {
+		t.Errorf("environment = %v, expected %v", sce.EnvironmentVariables, expected)
+	}
+	if expected := map[string]string{"matrix.runtime": "net6", "matrix.mode": "fast"}; !reflect.DeepEqual(sce.Tags, expected) {
+		t.Errorf("tags = %v, expected %v", sce.Tags, expected)
+	}
+	if sce.WorkingDirectory == nil || *sce.WorkingDirectory != "/tmp" {
+		t.Errorf("working directory = %v, expected /tmp", sce.WorkingDirectory)
+	}
+	if expected := []string{"run"}; !reflect.DeepEqual(base.ProcessArguments.Values(), expected) {
+		t.Errorf("base arguments modified: %q", base.ProcessArguments.Values())
+	}
+}
+
+func TestMatrixValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{name: "no axes", json: `{}`},
+		{name: "duplicated axis", json: `{"axes": [{"name": "a", "values": [{"name": "x"}]}, {"name": "a", "values": [{"name": "y"}]}]}`},
+		{name: "duplicated value", json: `{"axes": [{"name": "a", "values": [{"name": "x"}, {"name": "x"}]}]}`},
+		{name: "unknown axis in rule", json: `{"axes": [{"name": "a", "values": [{"name": "x"}]}], "exclude": [{"b": "x"}]}`},
+		{name: "invalid pattern", json: `{"axes": [{"name": "a", "values": [{"name": "x"}]}], "include": [{"a": "["}]}`},
+		{name: "unknown axis in name", json: `{"axes": [{"name": "a", "values": [{"name": "x"}]}], "name": "{b}"}`},
+	}
+	for _, test := range tests {
+		var m matrix
+		if err := json.Unmarshal([]byte(test.json), &m); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := m.validate(); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
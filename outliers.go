@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/montanaflynn/stats"
+)
+
+const (
+	outlierPolicyNone    = "none"
+	outlierPolicyMild    = "mild"
+	outlierPolicyExtreme = "extreme"
+	outlierPolicyMAD     = "mad"
+	outlierPolicyTrim    = "trim"
+
+	defaultMADThreshold = 3.5
+	defaultTrimPercent  = 5
+)
+
+// outlierPolicy configures how the outliers are detected and removed from the statistics
+type outlierPolicy struct {
+	Policy    string  `json:"policy"`
+	Threshold float64 `json:"threshold"`
+	Percent   float64 `json:"percent"`
+}
+
+// defaultOutlierPolicy removes the extreme quartile outliers
+var defaultOutlierPolicy = outlierPolicy{Policy: outlierPolicyExtreme}
+
+func (o *outlierPolicy) validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.Policy {
+	case "":
+		o.Policy = outlierPolicyExtreme
+	case outlierPolicyNone, outlierPolicyMild, outlierPolicyExtreme:
+	case outlierPolicyMAD:
+		if o.Threshold == 0 {
+			o.Threshold = defaultMADThreshold
+		} else if o.Threshold < 0 {
+			return errors.New("outliers threshold must be greater than 0")
+		}
+	case outlierPolicyTrim:
+		if o.Percent == 0 {
+			o.Percent = defaultTrimPercent
+		} else if o.Percent < 0 || o.Percent >= 50 {
+			return errors.New("outliers percent must be between 0 and 50")
+		}
+	default:
+		return fmt.Errorf("invalid outliers policy '%s', valid values are: %s, %s, %s, %s, %s", o.Policy,
+			outlierPolicyNone, outlierPolicyMild, outlierPolicyExtreme, outlierPolicyMAD, outlierPolicyTrim)
+	}
+	return nil
+}
+
+// String returns the policy with its parameter
+func (o *outlierPolicy) String() string {
+	switch o.Policy {
+	case outlierPolicyMAD:
+		return fmt.Sprintf("%s (threshold: %v)", o.Policy, o.Threshold)
+	case outlierPolicyTrim:
+		return fmt.Sprintf("%s (percent: %v)", o.Policy, o.Percent)
+	}
+	return o.Policy
+}
+
+// outlierIndexes returns the positions of the outliers in the values
+func (o *outlierPolicy) outlierIndexes(values []float64) map[int]bool {
+	indexes := map[int]bool{}
+	if len(values) == 0 {
+		return indexes
+	}
+
+	switch o.Policy {
+	case outlierPolicyMild, outlierPolicyExtreme:
+		// Tukey's fences, mild outliers are beyond 1.5 IQR and extreme outliers beyond 3 IQR
+		quartiles, err := stats.Quartile(values)
+		if err != nil {
+			return indexes
+		}
+		factor := 3.0
+		if o.Policy == outlierPolicyMild {
+			factor = 1.5
+		}
+		iqr := quartiles.Q3 - quartiles.Q1
+		lower := quartiles.Q1 - factor*iqr
+		upper := quartiles.Q3 + factor*iqr
+		for idx, value := range values {
+			if value < lower || value > upper {
+				indexes[idx] = true
+			}
+		}
+
+	case outlierPolicyMAD:
+		// modified z-score using the median absolute deviation (Iglewicz and Hoaglin)
+		median, _ := stats.Median(values)
+		mad, _ := stats.MedianAbsoluteDeviation(values)
+		if mad == 0 {
+			return indexes
+		}
+		for idx, value := range values {
+			if math.Abs(0.6745*(value-median)/mad) > o.Threshold {
+				indexes[idx] = true
+			}
+		}
+
+	case outlierPolicyTrim:
+		// symmetric trimming of the same number of lowest and highest values
+		trimCount := int(float64(len(values)) * o.Percent / 100)
+		sorted := make([]int, len(values))
+		for idx := range sorted {
+			sorted[idx] = idx
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return values[sorted[i]] < values[sorted[j]]
+		})
+		for i := 0; i < trimCount; i++ {
+			indexes[sorted[i]] = true
+			indexes[sorted[len(sorted)-1-i]] = true
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOutlierIndexes(t *testing.T) {
+	// Q1 = 12, Q3 = 17: mild fences are 4.5 and 24.5, extreme fences are -3 and 32
+	// median = 14.5, MAD = 2.5: modified z-scores are 4.18 for 30 and 23.06 for 100
+	values := []float64{30, 10, 11, 100, 12, 13, 14, 15, 16, 17}
+	tests := []struct {
+		name     string
+		policy   outlierPolicy
+		values   []float64
+		expected []int
+	}{
+		{name: "none", policy: outlierPolicy{Policy: outlierPolicyNone}, values: values, expected: nil},
+		{name: "mild", policy: outlierPolicy{Policy: outlierPolicyMild}, values: values, expected: []int{0, 3}},
+		{name: "extreme", policy: outlierPolicy{Policy: outlierPolicyExtreme}, values: values, expected: []int{3}},
+		{name: "mad", policy: outlierPolicy{Policy: outlierPolicyMAD, Threshold: 3.5}, values: values, expected: []int{0, 3}},
+		{name: "mad threshold", policy: outlierPolicy{Policy: outlierPolicyMAD, Threshold: 5}, values: values, expected: []int{3}},
+		{name: "mad constant", policy: outlierPolicy{Policy: outlierPolicyMAD, Threshold: 3.5}, values: []float64{5, 5, 5, 5}, expected: nil},
+		{name: "trim", policy: outlierPolicy{Policy: outlierPolicyTrim, Percent: 10}, values: values, expected: []int{1, 3}},
+		{name: "trim 20%", policy: outlierPolicy{Policy: outlierPolicyTrim, Percent: 20}, values: values, expected: []int{0, 1, 2, 3}},
+		{name: "trim ties", policy: outlierPolicy{Policy: outlierPolicyTrim, Percent: 25}, values: []float64{5, 5, 5, 5}, expected: []int{0, 3}},
+		{name: "trim few values", policy: outlierPolicy{Policy: outlierPolicyTrim, Percent: 5}, values: values, expected: nil},
+		{name: "empty", policy: outlierPolicy{Policy: outlierPolicyExtreme}, values: nil, expected: nil},
+	}
+	for _, test := range tests {
+		indexes := test.policy.outlierIndexes(test.values)
+		var actual []int
+		for idx := range test.values {
+			if indexes[idx] {
+				actual = append(actual, idx)
+			}
+		}
+		if len(indexes) != len(actual) || !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: outliers = %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestOutlierPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   outlierPolicy
+		expected *outlierPolicy
+	}{
+		{name: "default", policy: outlierPolicy{}, expected: &outlierPolicy{Policy: outlierPolicyExtreme}},
+		{name: "mad default threshold", policy: outlierPolicy{Policy: outlierPolicyMAD}, expected: &outlierPolicy{Policy: outlierPolicyMAD, Threshold: defaultMADThreshold}},
+		{name: "trim default percent", policy: outlierPolicy{Policy: outlierPolicyTrim}, expected: &outlierPolicy{Policy: outlierPolicyTrim, Percent: defaultTrimPercent}},
+		{name: "mad negative threshold", policy: outlierPolicy{Policy: outlierPolicyMAD, Threshold: -1}, expected: nil},
+		{name: "trim half", policy: outlierPolicy{Policy: outlierPolicyTrim, Percent: 50}, expected: nil},
+		{name: "unknown", policy: outlierPolicy{Policy: "iqr"}, expected: nil},
+	}
+	for _, test := range tests {
+		err := test.policy.validate()
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil || test.policy != *test.expected {
+			t.Errorf("%s: validate() = %v, policy = %+v", test.name, err, test.policy)
+		}
+	}
+}
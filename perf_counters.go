@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// perfCounterMetrics maps the supported perf counters to the name of the metric where their values are recorded
+var perfCounterMetrics = map[string]string{
+	"instructions":     "process.perf.instructions",
+	"cycles":           "process.perf.cycles",
+	"branches":         "process.perf.branches",
+	"branch-misses":    "process.perf.branch_misses",
+	"cache-references": "process.perf.cache_references",
+	"cache-misses":     "process.perf.cache_misses",
+	"task-clock":       "process.perf.task_clock_ms",
+	"context-switches": "process.perf.context_switches",
+	"cpu-migrations":   "process.perf.cpu_migrations",
+	"page-faults":      "process.perf.page_faults",
+}
+
+func (p *processData) validatePerfCounters() error {
+	for _, name := range p.PerfCounters {
+		if _, ok := perfCounterMetrics[name]; !ok {
+			var names []string
+			for k := range perfCounterMetrics {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("invalid perf counter '%s', valid values are: %s", name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// addPerfCounterMetrics adds the perf counter values to a metrics map
+func addPerfCounterMetrics(values map[string]float64, metrics map[string]float64) {
+	for name, value := range values {
+		metrics[perfCounterMetrics[name]] = value
+	}
+}
+
+// printPerfCountersWarnings prints a warning for every configured perf counter that can't be opened
+func printPerfCountersWarnings(scenarios []scenario) {
+	probed := map[string]bool{}
+	warnings := false
+	for _, sce := range scenarios {
+		for _, name := range sce.PerfCounters {
+			if probed[name] {
+				continue
+			}
+			probed[name] = true
+			if err := probePerfCounter(name); err != nil {
+				warnings = true
+				fmt.Printf("Warning: perf counter '%s' is not available and will be ignored: %v\n", name, err)
+				if perfCountersSupported {
+					fmt.Print("  check /proc/sys/kernel/perf_event_paranoid and the container restrictions (CAP_PERFMON, seccomp).\n")
+				}
+			}
+		}
+	}
+	if warnings {
+		fmt.Println()
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const perfCountersSupported = true
+
+const (
+	perfTypeHardware = 0
+	perfTypeSoftware = 1
+
+	perfFormatTotalTimeEnabled = 1 << 0
+	perfFormatTotalTimeRunning = 1 << 1
+
+	perfFlagDisabled      = 1 << 0
+	perfFlagInherit       = 1 << 1
+	perfFlagExcludeKernel = 1 << 5
+	perfFlagExcludeHv     = 1 << 6
+	perfFlagEnableOnExec  = 1 << 12
+
+	perfFlagFdCloexec = 1 << 3
+)
+
+type (
+	// perfEventAttr is the perf_event_attr struct (PERF_ATTR_SIZE_VER0)
+	perfEventAttr struct {
+		Type         uint32
+		Size         uint32
+		Config       uint64
+		SamplePeriod uint64
+		SampleType   uint64
+		ReadFormat   uint64
+		Flags        uint64
+		WakeupEvents uint32
+		BpType       uint32
+		BpAddr       uint64
+	}
+
+	perfEvent struct {
+		eventType uint32
+		config    uint64
+	}
+
+	// perfCounters holds the perf events file descriptors opened for a process
+	perfCounters struct {
+		fds map[string]int
+	}
+)
+
+var perfEvents = map[string]perfEvent{
+	"cycles":           {perfTypeHardware, 0},
+	"instructions":     {perfTypeHardware, 1},
+	"cache-references": {perfTypeHardware, 2},
+	"cache-misses":     {perfTypeHardware, 3},
+	"branches":         {perfTypeHardware, 4},
+	"branch-misses":    {perfTypeHardware, 5},
+	"task-clock":       {perfTypeSoftware, 1},
+	"page-faults":      {perfTypeSoftware, 2},
+	"context-switches": {perfTypeSoftware, 3},
+	"cpu-migrations":   {perfTypeSoftware, 4},
+}
+
+// openPerfCounter opens a perf event for the current thread. The event is inherited by the
+// processes forked by the thread and only enabled once they exec, so it doesn't count the
+// thread itself. If the kernel events are not allowed (perf_event_paranoid) only the user
+// space is counted.
+func openPerfCounter(name string) (int, error) {
+	event := perfEvents[name]
+	attr := perfEventAttr{
+		Type:       event.eventType,
+		Size:       uint32(unsafe.Sizeof(perfEventAttr{})),
+		Config:     event.config,
+		ReadFormat: perfFormatTotalTimeEnabled | perfFormatTotalTimeRunning,
+		Flags:      perfFlagDisabled | perfFlagInherit | perfFlagEnableOnExec | perfFlagExcludeHv,
+	}
+	fd, err := perfEventOpen(&attr)
+	if err == syscall.EACCES || err == syscall.EPERM {
+		attr.Flags |= perfFlagExcludeKernel
+		fd, err = perfEventOpen(&attr)
+	}
+	return fd, err
+}
+
+func perfEventOpen(attr *perfEventAttr) (int, error) {
+	fd, _, errno := syscall.Syscall6(syscall.SYS_PERF_EVENT_OPEN, uintptr(unsafe.Pointer(attr)),
+		0, ^uintptr(0), ^uintptr(0), perfFlagFdCloexec, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// probePerfCounter returns an error if the perf counter can't be opened
+func probePerfCounter(name string) error {
+	fd, err := openPerfCounter(name)
+	if err != nil {
+		return err
+	}
+	return syscall.Close(fd)
+}
+
+// openPerfCounters opens the perf counters for the processes started by the current thread,
+// the counters that can't be opened are skipped.
+func openPerfCounters(names []string) *perfCounters {
+	if len(names) == 0 {
+		return nil
+	}
+	counters := &perfCounters{fds: map[string]int{}}
+	for _, name := range names {
+		if fd, err := openPerfCounter(name); err == nil {
+			counters.fds[name] = fd
+		}
+	}
+	return counters
+}
+
+// read returns the values of the counters, scaled if the counters were multiplexed.
+// The values of the inherited counters are available once the processes have exited.
+func (p *perfCounters) read() map[string]float64 {
+	if p == nil || len(p.fds) == 0 {
+		return nil
+	}
+	values := map[string]float64{}
+	// value, time enabled and time running in the native byte order
+	var data [3]uint64
+	buffer := (*[24]byte)(unsafe.Pointer(&data[0]))[:]
+	for name, fd := range p.fds {
+		if n, err := syscall.Read(fd, buffer); err != nil || n != len(buffer) {
+			continue
+		}
+		value := float64(data[0])
+		enabled := data[1]
+		running := data[2]
+		if running > 0 && running < enabled {
+			value = value * float64(enabled) / float64(running)
+		}
+		if name == "task-clock" {
+			// task-clock is measured in nanoseconds
+			value = value / float64(time.Millisecond)
+		}
+		values[name] = value
+	}
+	return values
+}
+
+func (p *perfCounters) close() {
+	if p == nil {
+		return
+	}
+	for _, fd := range p.fds {
+		_ = syscall.Close(fd)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+const perfCountersSupported = false
+
+// perfCounters is only supported on Linux
+type perfCounters struct{}
+
+// probePerfCounter is only supported on Linux
+func probePerfCounter(name string) error {
+	return errors.New("perf counters are only supported on Linux")
+}
+
+func (p *perfCounters) read() map[string]float64 {
+	return nil
+}
+
+func (p *perfCounters) close() {
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// activeProcesses are the started process groups that must not outlive timeit
+var activeProcesses = struct {
+	sync.Mutex
+	cmds map[*exec.Cmd]struct{}
+}{cmds: map[*exec.Cmd]struct{}{}}
+
+// trackProcess registers a started command, so its process group is killed if timeit is interrupted
+func trackProcess(cmd *exec.Cmd) {
+	activeProcesses.Lock()
+	defer activeProcesses.Unlock()
+	activeProcesses.cmds[cmd] = struct{}{}
+}
+
+// untrackProcess removes a command once it has been waited
+func untrackProcess(cmd *exec.Cmd) {
+	activeProcesses.Lock()
+	defer activeProcesses.Unlock()
+	delete(activeProcesses.cmds, cmd)
+}
+
+// killActiveProcesses kills the process groups of all the tracked commands
+func killActiveProcesses() {
+	activeProcesses.Lock()
+	defer activeProcesses.Unlock()
+	for cmd := range activeProcesses.cmds {
+		_ = killProcessGroup(cmd)
+	}
+}
+
+// handleInterrupts kills the active process groups before exiting on SIGINT or SIGTERM, the children
+// run in their own process groups so they don't receive the signals sent to timeit
+func handleInterrupts() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		killActiveProcesses()
+		fmt.Fprintf(os.Stderr, "\nInterrupted by %v\n", sig)
+		exitCode := 1
+		if s, ok := sig.(syscall.Signal); ok {
+			exitCode = 128 + int(s)
+		}
+		os.Exit(exitCode)
+	}()
+}
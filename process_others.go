@@ -0,0 +1,29 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is not supported on this platform
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// terminateProcessGroup sends an interrupt signal to the process of the command
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Signal(os.Interrupt)
+}
+
+// killProcessGroup kills the process of the command
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
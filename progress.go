@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/montanaflynn/stats"
+)
+
+const (
+	progressModeTTY     = "tty"
+	progressModePlain   = "plain"
+	progressModeVerbose = "verbose"
+	progressModeQuiet   = "quiet"
+
+	ttyProgressRefresh    = 100 * time.Millisecond
+	plainProgressInterval = 10 * time.Second
+	progressBarWidth      = 20
+)
+
+type (
+	// progressRenderer displays the progress of the scenario runs
+	progressRenderer interface {
+		// begin is called when a phase (calibration, warmup or run) of the runs starts
+		begin(label string, runs []*scenarioRun)
+		// iteration is called after each iteration of a run
+		iteration(run *scenarioRun, dataPoint *scenarioDataPoint)
+		// retry is called when a failed iteration is retried
+		retry(run *scenarioRun, dataPoint *scenarioDataPoint)
+		// end is called when all the runs of the phase are done
+		end()
+		// message prints a line in the middle of a phase without breaking the progress output
+		message(text string)
+	}
+
+	// ttyProgress redraws a progress bar per scenario in interactive terminals
+	ttyProgress struct {
+		runs       []*scenarioRun
+		lines      int
+		lastRender time.Time
+	}
+
+	// plainProgress prints a summary of the runs periodically, suitable for CI logs
+	plainProgress struct {
+		runs       []*scenarioRun
+		lastReport time.Time
+	}
+
+	// verboseProgress prints a line for each iteration
+	verboseProgress struct {
+		runs []*scenarioRun
+	}
+
+	// quietProgress doesn't print anything
+	quietProgress struct{}
+
+	// runProgress is a snapshot of the progress of a run
+	runProgress struct {
+		done     int
+		total    int
+		failures int
+		mean     float64
+		percent  float64
+		eta      time.Duration
+		hasETA   bool
+	}
+)
+
+var progress progressRenderer = &plainProgress{}
+var quietMode bool
+
+// newProgressRenderer returns the renderer of the mode, when the mode is empty
+// the tty mode is used if the standard output is a terminal.
+func newProgressRenderer(mode string) progressRenderer {
+	if mode == "" {
+		mode = progressModePlain
+		if isTerminal(os.Stdout) {
+			mode = progressModeTTY
+		}
+	}
+	switch mode {
+	case progressModeTTY:
+		return &ttyProgress{}
+	case progressModeVerbose:
+		return &verboseProgress{}
+	case progressModeQuiet:
+		return quietProgress{}
+	default:
+		return &plainProgress{}
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printInfo prints an informational message, nothing is printed in quiet mode
+func printInfo(format string, a ...interface{}) {
+	if !quietMode {
+		fmt.Printf(format, a...)
+	}
+}
+
+// getProgress returns the progress of the run, the total and ETA are estimated from the count,
+// the adaptive or automatic warmup maximum count and the time budgets
+func (r *scenarioRun) getProgress() runProgress {
+	p := runProgress{done: len(r.data)}
+	var durations []float64
+	for _, item := range r.data {
+		if item.Error != nil {
+			p.failures++
+		} else {
+			durations = append(durations, float64(item.Duration))
+		}
+	}
+	if len(durations) > 0 {
+		p.mean, _ = stats.Mean(durations)
+	}
+
+	if r.autoWarmUp != nil {
+		p.total = r.autoWarmUp.MaxCount
+	} else if r.adaptive != nil {
+		p.total = r.adaptive.MaxCount
+	} else {
+		p.total = r.count
+	}
+
+	var elapsed time.Duration
+	if !r.start.IsZero() {
+		elapsed = time.Since(r.start)
+	}
+	if p.total > 0 && p.done > 0 {
+		p.percent = math.Min(float64(p.done)/float64(p.total), 1)
+		p.eta = time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+		p.hasETA = true
+	}
+	budget := r.maxRunTime
+	if budget == 0 {
+		budget = r.maxDuration
+	}
+	if budget > 0 && (!p.hasETA || elapsed+p.eta > budget) {
+		p.percent = math.Min(float64(elapsed)/float64(budget), 1)
+		p.eta = budget - elapsed
+		p.hasETA = true
+	}
+	if r.minRunTime > elapsed && (!p.hasETA || elapsed+p.eta < r.minRunTime) {
+		p.percent = float64(elapsed) / float64(r.minRunTime)
+		p.eta = r.minRunTime - elapsed
+		p.hasETA = true
+	}
+	if r.stopReason != "" {
+		p.percent = 1
+		p.eta = 0
+	}
+	return p
+}
+
+// String returns the progress summary
+func (p runProgress) String() string {
+	var parts []string
+	if p.total > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d", p.done, p.total))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d", p.done))
+	}
+	if p.mean > 0 {
+		parts = append(parts, fmt.Sprintf("mean: %v", time.Duration(p.mean).Round(time.Microsecond)))
+	}
+	parts = append(parts, fmt.Sprintf("failures: %d", p.failures))
+	if p.hasETA {
+		parts = append(parts, fmt.Sprintf("ETA: %v", p.eta.Round(time.Second)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p runProgress) bar() string {
+	filled := int(p.percent * progressBarWidth)
+	return fmt.Sprintf("[%s%s] %3.0f%%", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), p.percent*100)
+}
+
+func progressName(run *scenarioRun, runs []*scenarioRun) string {
+	if len(runs) > 1 {
+		return run.scenario.Name + " "
+	}
+	return ""
+}
+
+func (t *ttyProgress) begin(label string, runs []*scenarioRun) {
+	fmt.Println(label)
+	t.runs = runs
+	t.lines = 0
+	t.render()
+}
+
+func (t *ttyProgress) iteration(run *scenarioRun, dataPoint *scenarioDataPoint) {
+	if time.Since(t.lastRender) >= ttyProgressRefresh {
+		t.render()
+	}
+}
+
+func (t *ttyProgress) retry(run *scenarioRun, dataPoint *scenarioDataPoint) {
+}
+
+func (t *ttyProgress) end() {
+	t.render()
+	t.runs = nil
+}
+
+// message replaces the progress lines with the message and redraws them below it
+func (t *ttyProgress) message(text string) {
+	if t.lines > 0 {
+		fmt.Printf("\033[%dA", t.lines)
+	}
+	fmt.Printf("\r\033[K    %s\n", text)
+	t.lines = 0
+	if len(t.runs) > 0 {
+		t.render()
+	}
+}
+
+// render moves the cursor up to the first progress line and redraws all of them
+func (t *ttyProgress) render() {
+	if t.lines > 0 {
+		fmt.Printf("\033[%dA", t.lines)
+	}
+	for _, run := range t.runs {
+		p := run.getProgress()
+		fmt.Printf("\r\033[K    %s%s  %v\n", progressName(run, t.runs), p.bar(), p)
+	}
+	t.lines = len(t.runs)
+	t.lastRender = time.Now()
+}
+
+func (p *plainProgress) begin(label string, runs []*scenarioRun) {
+	fmt.Println(label)
+	p.runs = runs
+	p.lastReport = time.Now()
+}
+
+func (p *plainProgress) iteration(run *scenarioRun, dataPoint *scenarioDataPoint) {
+	if time.Since(p.lastReport) >= plainProgressInterval {
+		p.report()
+	}
+}
+
+func (p *plainProgress) retry(run *scenarioRun, dataPoint *scenarioDataPoint) {
+}
+
+func (p *plainProgress) end() {
+	p.report()
+	p.runs = nil
+}
+
+func (p *plainProgress) message(text string) {
+	fmt.Printf("    %s\n", text)
+}
+
+func (p *plainProgress) report() {
+	for _, run := range p.runs {
+		fmt.Printf("    %s%v\n", progressName(run, p.runs), run.getProgress())
+	}
+	p.lastReport = time.Now()
+}
+
+func (v *verboseProgress) begin(label string, runs []*scenarioRun) {
+	fmt.Println(label)
+	v.runs = runs
+}
+
+func (v *verboseProgress) iteration(run *scenarioRun, dataPoint *scenarioDataPoint) {
+	line := fmt.Sprintf("    %s#%d %s %v", progressName(run, v.runs), len(run.data), dataPoint.Status, dataPoint.Duration)
+	if dataPoint.ExitCode != nil {
+		line += fmt.Sprintf(" (exit code: %d)", *dataPoint.ExitCode)
+	}
+	if dataPoint.Error != nil {
+		line += ": " + lastLine(dataPoint.Error.Error())
+	}
+	fmt.Println(line)
+}
+
+func (v *verboseProgress) retry(run *scenarioRun, dataPoint *scenarioDataPoint) {
+	line := fmt.Sprintf("    %s#%d retrying after %s", progressName(run, v.runs), len(run.data)+1, dataPoint.Status)
+	if dataPoint.Error != nil {
+		line += ": " + lastLine(dataPoint.Error.Error())
+	}
+	fmt.Println(line)
+}
+
+func (v *verboseProgress) end() {
+	v.runs = nil
+}
+
+func (v *verboseProgress) message(text string) {
+	fmt.Printf("    %s\n", text)
+}
+
+func (quietProgress) begin(label string, runs []*scenarioRun) {
+}
+
+func (quietProgress) iteration(run *scenarioRun, dataPoint *scenarioDataPoint) {
+}
+
+func (quietProgress) retry(run *scenarioRun, dataPoint *scenarioDataPoint) {
+}
+
+func (quietProgress) end() {
+}
+
+func (quietProgress) message(text string) {
+}
+
+// lastLine returns the last line of an error message, which usually contains the cause
+func lastLine(value string) string {
+	lines := strings.Split(strings.TrimSpace(value), "\n")
+	return lines[len(lines)-1]
+}
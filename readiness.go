@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+const (
+	readinessStreamBoth   = "both"
+	readinessStreamStdout = "stdout"
+	readinessStreamStderr = "stderr"
+
+	// maxReadinessLineLength limits the memory used by a line without a line break
+	maxReadinessLineLength = 64 * 1024
+)
+
+type (
+	// readiness configures the measurement of the time until the process is ready
+	readiness struct {
+		Pattern   string `json:"pattern"`
+		Stream    string `json:"stream"`
+		Terminate bool   `json:"terminate"`
+		pattern   *regexp.Regexp
+	}
+
+	// outputWatcher records when the first output is written and when the readiness pattern matches
+	outputWatcher struct {
+		mutex          sync.Mutex
+		readiness      *readiness
+		onReady        func()
+		hasFirstOutput bool
+		firstOutput    time.Duration
+		isReady        bool
+		ready          time.Duration
+	}
+
+	// watchedStream is the writer of an output stream of the process
+	watchedStream struct {
+		watcher *outputWatcher
+		line    []byte
+	}
+)
+
+func (r *readiness) compile() error {
+	if r == nil {
+		return nil
+	}
+	switch r.Stream {
+	case "":
+		r.Stream = readinessStreamBoth
+	case readinessStreamBoth, readinessStreamStdout, readinessStreamStderr:
+	default:
+		return fmt.Errorf("invalid readiness stream '%s', valid values are: %s, %s, %s",
+			r.Stream, readinessStreamBoth, readinessStreamStdout, readinessStreamStderr)
+	}
+	r.pattern = nil
+	if r.Pattern != "" {
+		rgx, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid readiness pattern '%s': %v", r.Pattern, err)
+		}
+		r.pattern = rgx
+	} else if r.Terminate {
+		return errors.New("readiness terminate requires a pattern")
+	}
+	return nil
+}
+
+func newOutputWatcher(readiness *readiness, onReady func()) *outputWatcher {
+	return &outputWatcher{
+		readiness: readiness,
+		onReady:   onReady,
+	}
+}
+
+// wrap returns the writers with the watcher for the stream appended if the stream is watched
+func (w *outputWatcher) wrap(stream string, writers ...io.Writer) io.Writer {
+	if w != nil && (w.readiness.Stream == readinessStreamBoth || w.readiness.Stream == stream) {
+		writers = append(writers, &watchedStream{watcher: w})
+	}
+	return io.MultiWriter(writers...)
+}
+
+// matched returns true if the readiness pattern has matched
+func (w *outputWatcher) matched() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.isReady
+}
+
+// results returns the time to the first output and the time to the readiness pattern match since the start time
+func (w *outputWatcher) results(start time.Duration) (*time.Duration, *time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var timeToFirstOutput, timeToReady *time.Duration
+	if w.hasFirstOutput {
+		value := w.firstOutput - start
+		timeToFirstOutput = &value
+	}
+	if w.isReady {
+		value := w.ready - start
+		timeToReady = &value
+	}
+	return timeToFirstOutput, timeToReady
+}
+
+func (s *watchedStream) Write(p []byte) (int, error) {
+	now := hrtime.Now()
+	w := s.watcher
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasFirstOutput {
+		w.hasFirstOutput = true
+		w.firstOutput = now
+	}
+	if w.readiness.pattern == nil || w.isReady {
+		return len(p), nil
+	}
+
+	// the pattern is evaluated on each complete line and on the pending partial line (e.g. prompts)
+	s.line = append(s.line, p...)
+	for !w.isReady {
+		idx := bytes.IndexByte(s.line, '\n')
+		if idx < 0 {
+			break
+		}
+		w.isReady = w.readiness.pattern.Match(s.line[:idx])
+		s.line = s.line[idx+1:]
+	}
+	if !w.isReady && len(s.line) > 0 {
+		w.isReady = w.readiness.pattern.Match(s.line)
+	}
+	if len(s.line) > maxReadinessLineLength {
+		s.line = s.line[len(s.line)-maxReadinessLineLength:]
+	}
+
+	if w.isReady {
+		w.ready = now
+		s.line = nil
+		if w.readiness.Terminate && w.onReady != nil {
+			w.onReady()
+		}
+	}
+	return len(p), nil
+}
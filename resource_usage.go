@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// resourceUsage contains the resource usage of a finished process
+type resourceUsage struct {
+	UserTime                   time.Duration `json:"userTime"`
+	SystemTime                 time.Duration `json:"systemTime"`
+	MaxRSS                     int64         `json:"maxRss"`
+	MinorPageFaults            int64         `json:"minorPageFaults"`
+	MajorPageFaults            int64         `json:"majorPageFaults"`
+	VoluntaryContextSwitches   int64         `json:"voluntaryContextSwitches"`
+	InvoluntaryContextSwitches int64         `json:"involuntaryContextSwitches"`
+}
+
+func getResourceUsage(state *os.ProcessState) *resourceUsage {
+	if state == nil {
+		return nil
+	}
+	usage := &resourceUsage{
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+	}
+	fillSystemResourceUsage(state, usage)
+	return usage
+}
+
+// addMetrics adds the resource usage values to a metrics map, so they get
+// aggregated and exported as any other metric.
+func (u *resourceUsage) addMetrics(metrics map[string]float64) {
+	if u == nil {
+		return
+	}
+	metrics["process.cpu.user_time_ms"] = float64(u.UserTime) / float64(time.Millisecond)
+	metrics["process.cpu.system_time_ms"] = float64(u.SystemTime) / float64(time.Millisecond)
+	if hasSystemResourceUsage {
+		metrics["process.memory.max_rss_bytes"] = float64(u.MaxRSS)
+		metrics["process.memory.minor_page_faults"] = float64(u.MinorPageFaults)
+		metrics["process.memory.major_page_faults"] = float64(u.MajorPageFaults)
+		metrics["process.context_switches.voluntary"] = float64(u.VoluntaryContextSwitches)
+		metrics["process.context_switches.involuntary"] = float64(u.InvoluntaryContextSwitches)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+const hasSystemResourceUsage = true
+
+func fillSystemResourceUsage(state *os.ProcessState, usage *resourceUsage) {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return
+	}
+
+	// ru_maxrss is reported in bytes on darwin and in kilobytes everywhere else
+	usage.MaxRSS = int64(rusage.Maxrss)
+	if runtime.GOOS != "darwin" {
+		usage.MaxRSS *= 1024
+	}
+	usage.MinorPageFaults = int64(rusage.Minflt)
+	usage.MajorPageFaults = int64(rusage.Majflt)
+	usage.VoluntaryContextSwitches = int64(rusage.Nvcsw)
+	usage.InvoluntaryContextSwitches = int64(rusage.Nivcsw)
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	executionOrderSequential = "sequential"
+	executionOrderRoundRobin = "roundRobin"
+	executionOrderShuffled   = "shuffled"
+
+	adaptiveCriterionStdErr             = "stdErr"
+	adaptiveCriterionConfidenceInterval = "confidenceInterval"
+
+	stopReasonCount            = "count"
+	stopReasonPrecisionReached = "precisionReached"
+	stopReasonMaxCount         = "maxCount"
+	stopReasonMaxDuration      = "maxDuration"
+	stopReasonError            = "error"
+	stopReasonFailFast         = "failFast"
+	stopReasonMaxFailures      = "maxFailures"
+	stopReasonMaxFailureRatio  = "maxFailureRatio"
+	stopReasonAborted          = "aborted"
+	stopReasonSteadyState      = "steadyState"
+	stopReasonMaxRunTime       = "maxRunTime"
+	stopReasonMinRunTime       = "minRunTime"
+	stopReasonMaxTotalRunTime  = "maxTotalRunTime"
+
+	dataPointStatusSuccess = "success"
+	dataPointStatusError   = "error"
+	dataPointStatusTimeout = "timeout"
+	dataPointStatusFailed  = "failed"
+)
+
+// deadline is the time limit of the whole configuration, zero if there's no limit
+var deadline time.Time
+
+// deadlineExceeded returns true if the maxTotalRunTime of the configuration was exhausted
+func deadlineExceeded() bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+// errDeadlineExceeded is the error of the scenarios skipped after the deadline
+var errDeadlineExceeded = errors.New("the scenario was skipped after exhausting maxTotalRunTime")
+
+// scenarioRun holds the state of a scenario while its iterations are being executed
+type scenarioRun struct {
+	scenario    *scenario
+	count       int
+	maxDuration time.Duration
+	maxRunTime  time.Duration
+	minRunTime  time.Duration
+	adaptive    *adaptive
+	autoWarmUp  *autoWarmUp
+	warmUp      bool
+	data        []scenarioDataPoint
+	start       time.Time
+	end         time.Time
+	stopReason  string
+	precision   float64
+	failures    int
+	retries     int
+	calibration *calibrationResult
+}
+
+func newScenarioRun(scenario *scenario) *scenarioRun {
+	return &scenarioRun{
+		scenario:   scenario,
+		count:      *scenario.Count,
+		maxRunTime: time.Duration(*scenario.MaxRunTime) * time.Second,
+		minRunTime: time.Duration(*scenario.MinRunTime) * time.Second,
+		adaptive:   scenario.Adaptive,
+		precision:  math.Inf(1),
+	}
+}
+
+func newWarmUpRun(scenario *scenario) *scenarioRun {
+	run := newFixedRun(scenario, *scenario.WarmUpCount, time.Duration(*scenario.WarmUpDuration)*time.Second)
+	run.autoWarmUp = scenario.AutoWarmUp
+	return run
+}
+
+// newFixedRun returns a warmup run with a fixed count and duration budget
+func newFixedRun(scenario *scenario, count int, maxDuration time.Duration) *scenarioRun {
+	return &scenarioRun{
+		scenario:    scenario,
+		count:       count,
+		maxDuration: maxDuration,
+		warmUp:      true,
+		precision:   math.Inf(1),
+	}
+}
+
+// done returns true if the scenario doesn't need more iterations, in that case the stop reason is set
+func (r *scenarioRun) done() bool {
+	if r.stopReason != "" {
+		return true
+	}
+	if deadlineExceeded() {
+		r.stopReason = stopReasonMaxTotalRunTime
+		return true
+	}
+
+	count := len(r.data)
+	if r.autoWarmUp != nil {
+		if r.isSteadyState() {
+			r.stopReason = stopReasonSteadyState
+		} else if count >= r.autoWarmUp.MaxCount {
+			r.stopReason = stopReasonMaxCount
+		} else if r.maxDuration > 0 && !r.start.IsZero() && time.Since(r.start) >= r.maxDuration {
+			r.stopReason = stopReasonMaxDuration
+		}
+		return r.stopReason != ""
+	}
+
+	// the time budgets take precedence over the count and the adaptive criteria
+	var elapsed time.Duration
+	if !r.start.IsZero() {
+		elapsed = time.Since(r.start)
+	}
+	if r.maxRunTime > 0 && elapsed >= r.maxRunTime {
+		r.stopReason = stopReasonMaxRunTime
+		return true
+	}
+	if elapsed < r.minRunTime {
+		return false
+	}
+
+	if r.adaptive == nil {
+		// without a count the run lasts until the duration budget is exhausted
+		hasBudget := r.maxDuration > 0 || r.maxRunTime > 0 || r.minRunTime > 0
+		if (r.count > 0 || !hasBudget) && count >= r.count {
+			r.stopReason = stopReasonCount
+		} else if r.count == 0 && r.minRunTime > 0 && r.maxRunTime == 0 {
+			r.stopReason = stopReasonMinRunTime
+		} else if r.maxDuration > 0 && !r.start.IsZero() && time.Since(r.start) >= r.maxDuration {
+			r.stopReason = stopReasonMaxDuration
+		}
+		return r.stopReason != ""
+	}
+
+	if count >= r.adaptive.MinCount && r.precision <= r.adaptive.Target {
+		r.stopReason = stopReasonPrecisionReached
+	} else if count >= r.adaptive.MaxCount {
+		r.stopReason = stopReasonMaxCount
+	} else if r.adaptive.MaxDuration > 0 && !r.start.IsZero() &&
+		time.Since(r.start) >= time.Duration(r.adaptive.MaxDuration)*time.Second {
+		r.stopReason = stopReasonMaxDuration
+	}
+	return r.stopReason != ""
+}
+
+// isSteadyState returns true if the durations of the last window of iterations don't drift
+// more than the threshold of the automatic warmup
+func (r *scenarioRun) isSteadyState() bool {
+	durations := r.successfulDurations()
+	if len(durations) < r.autoWarmUp.Window {
+		return false
+	}
+	return relativeDrift(durations[len(durations)-r.autoWarmUp.Window:]) <= r.autoWarmUp.Threshold
+}
+
+// successfulDurations returns the durations of the iterations without errors
+func (r *scenarioRun) successfulDurations() []float64 {
+	var durations []float64
+	for _, item := range r.data {
+		if item.Error == nil {
+			durations = append(durations, float64(item.Duration))
+		}
+	}
+	return durations
+}
+
+// runNext runs the next iteration of the scenario
+func (r *scenarioRun) runNext() {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	phase := "run"
+	if r.warmUp {
+		phase = "warmup"
+	}
+	var currentRun scenarioDataPoint
+	for attempt := 0; ; attempt++ {
+		currentRun = runIteration(r.scenario)
+		// the output of every attempt is persisted, the failed attempts are the ones worth debugging
+		if err := r.scenario.Artifacts.write(r.scenario.Name, phase, len(r.data), attempt, &currentRun); err != nil {
+			progress.message(fmt.Sprintf("error writing artifacts: %v", err))
+		}
+		if currentRun.Status == dataPointStatusSuccess || !currentRun.shouldContinue || attempt >= r.scenario.Retries {
+			break
+		}
+		r.retries++
+		progress.retry(r, &currentRun)
+	}
+	// the output is already persisted, there's no need to keep it in memory
+	currentRun.stdout = nil
+	currentRun.stderr = nil
+	r.data = append(r.data, currentRun)
+	r.end = time.Now()
+	if r.adaptive != nil {
+		r.precision = r.adaptive.precision(r.successfulDurations())
+	}
+	if currentRun.Status != dataPointStatusSuccess && !r.warmUp {
+		r.failures++
+	}
+	if !currentRun.shouldContinue {
+		r.stopReason = stopReasonError
+	} else if currentRun.Status != dataPointStatusSuccess && !r.warmUp {
+		r.checkFailurePolicy()
+	}
+	progress.iteration(r, &currentRun)
+}
+
+// runIteration runs an iteration of the scenario including the beforeEach and afterEach commands
+func runIteration(sce *scenario) scenarioDataPoint {
+	if err := runScenarioCommands(sce.BeforeEach, sce); err != nil {
+		now := time.Now()
+		return scenarioDataPoint{
+			Start:          now,
+			End:            now,
+			Status:         dataPointStatusError,
+			Error:          errors.New(fmt.Sprintf("\nbeforeEach %s", err.Error())),
+			shouldContinue: false,
+		}
+	}
+
+	var currentRun scenarioDataPoint
+	if sce.HTTPProbe != nil {
+		currentRun = runHTTPProbe(sce)
+	} else {
+		currentRun = runProcessCmd(sce)
+	}
+
+	if err := runScenarioCommands(sce.AfterEach, sce); err != nil {
+		if currentRun.Error != nil {
+			err = errors.New(fmt.Sprintf("%s\nafterEach %s", currentRun.Error.Error(), err.Error()))
+		} else {
+			err = errors.New(fmt.Sprintf("\nafterEach %s", err.Error()))
+		}
+		currentRun.Error = err
+		if currentRun.Status != dataPointStatusTimeout {
+			currentRun.Status = dataPointStatusError
+		}
+		currentRun.shouldContinue = false
+	}
+	return currentRun
+}
+
+// runScenario runs all the iterations of a scenario
+func runScenario(run *scenarioRun, label string) {
+	progress.begin(label, []*scenarioRun{run})
+	for !run.done() {
+		run.runNext()
+	}
+	progress.end()
+}
+
+// runScenariosInterleaved runs the iterations of multiple scenarios interleaving them in rounds,
+// on each round an iteration of every pending scenario is executed in the given order.
+func runScenariosInterleaved(runs []*scenarioRun, order string, rnd *rand.Rand, label string) {
+	progress.begin(label, runs)
+	indexes := make([]int, len(runs))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	for {
+		pending := false
+		if order == executionOrderShuffled {
+			rnd.Shuffle(len(indexes), func(i, j int) {
+				indexes[i], indexes[j] = indexes[j], indexes[i]
+			})
+		}
+		for _, idx := range indexes {
+			run := runs[idx]
+			if run.done() {
+				continue
+			}
+			run.runNext()
+			pending = true
+			if run.stopReason == stopReasonFailFast {
+				for _, other := range runs {
+					if other.stopReason == "" {
+						other.stopReason = stopReasonAborted
+					}
+				}
+				break
+			}
+		}
+		if !pending {
+			break
+		}
+	}
+	progress.end()
+}
+
+// processScenariosInterleaved runs the warmup and the measured iterations of all scenarios
+// interleaving the iterations across scenarios.
+func processScenariosInterleaved(scenarios []scenario, cfg *config, order string, rnd *rand.Rand) []scenarioResult {
+	results := make([]scenarioResult, len(scenarios))
+	runsByIndex := make([]*scenarioRun, len(scenarios))
+	warmUpsByIndex := make([]*scenarioRun, len(scenarios))
+	var warmUps []*scenarioRun
+	var runs []*scenarioRun
+	for idx := range scenarios {
+		if deadlineExceeded() {
+			results[idx] = getScenarioErrorResult(&scenarios[idx], errDeadlineExceeded)
+			continue
+		}
+		if err := runScenarioCommands(scenarios[idx].Setup, &scenarios[idx]); err != nil {
+			fmt.Printf("%v error in setup: %v\n", scenarios[idx].Name, err)
+			results[idx] = getScenarioErrorResult(&scenarios[idx], err)
+			continue
+		}
+		var calibration *calibrationResult
+		if cfg.Calibration != nil && scenarios[idx].HTTPProbe == nil {
+			var err error
+			if calibration, err = calibrateScenario(&scenarios[idx], cfg, fmt.Sprintf("Calibrating %v", scenarios[idx].Name)); err != nil {
+				fmt.Printf("%v error in calibration: %v\n", scenarios[idx].Name, err)
+				results[idx] = addScenarioError(getScenarioErrorResult(&scenarios[idx], err),
+					runScenarioCommands(scenarios[idx].Teardown, &scenarios[idx]))
+				continue
+			}
+			printInfo("    Baseline: %v\n", calibration)
+		}
+		warmUpsByIndex[idx] = newWarmUpRun(&scenarios[idx])
+		warmUps = append(warmUps, warmUpsByIndex[idx])
+		runsByIndex[idx] = newScenarioRun(&scenarios[idx])
+		runsByIndex[idx].calibration = calibration
+		runs = append(runs, runsByIndex[idx])
+	}
+
+	start := time.Now()
+	runScenariosInterleaved(warmUps, order, rnd, "Warming up")
+	printInfo("    Duration: %v\n", time.Since(start))
+	for _, warmUp := range warmUps {
+		if warmUp.autoWarmUp != nil {
+			printInfo("    %v stop reason: %v (%v iterations)\n", warmUp.scenario.Name, warmUp.stopReason, len(warmUp.data))
+		}
+	}
+	start = time.Now()
+	runScenariosInterleaved(runs, order, rnd, "Run")
+	printInfo("    Duration: %v\n", time.Since(start))
+	for _, run := range runs {
+		if run.stopReason != stopReasonCount {
+			printInfo("    %v stop reason: %v\n", run.scenario.Name, run.stopReason)
+		}
+	}
+
+	for idx, run := range runsByIndex {
+		if run == nil {
+			continue
+		}
+		teardownErr := runScenarioCommands(scenarios[idx].Teardown, &scenarios[idx])
+		if teardownErr != nil {
+			fmt.Printf("%v error in teardown: %v\n", scenarios[idx].Name, teardownErr)
+		}
+		result := getScenarioResult(run)
+		result.setWarmUp(warmUpsByIndex[idx])
+		result.setConfidenceIntervals(cfg.ConfidenceIntervals)
+		results[idx] = addScenarioError(result, teardownErr)
+	}
+	printInfo("\n")
+
+	return results
+}
+
+// precision returns the relative precision of the mean of the values using the configured criterion
+func (a *adaptive) precision(values []float64) float64 {
+	if a.Criterion == adaptiveCriterionConfidenceInterval {
+		return relativeConfidenceHalfWidth(values, a.ConfidenceLevel)
+	}
+	return relativeStdErr(values)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultSamplingInterval = 50
+
+type (
+	// sampling configures the polling of the measured process resources while it runs
+	sampling struct {
+		Interval           int  `json:"interval"`
+		IncludeDescendants bool `json:"includeDescendants"`
+	}
+
+	// processSample is a snapshot of the resources used by the measured process (and its descendants)
+	processSample struct {
+		rss     int64
+		threads int64
+	}
+
+	// processSampler polls the resources of a running process until it's stopped
+	processSampler struct {
+		settings     *sampling
+		pid          int
+		start        time.Time
+		last         time.Time
+		samples      int
+		peakRSS      int64
+		peakThreads  int64
+		totalThreads int64
+		cpuTicks     map[int]int64
+		stopCh       chan struct{}
+		waitGroup    sync.WaitGroup
+	}
+)
+
+func (s *sampling) validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Interval < 0 {
+		return errors.New("sampling interval must be greater than 0")
+	}
+	if s.Interval == 0 {
+		s.Interval = defaultSamplingInterval
+	}
+	return nil
+}
+
+// startSampling starts polling the resources of the process, it returns nil if sampling is not enabled or supported
+func startSampling(settings *sampling, pid int) *processSampler {
+	if settings == nil || !samplingSupported {
+		return nil
+	}
+	s := &processSampler{
+		settings: settings,
+		pid:      pid,
+		start:    time.Now(),
+		cpuTicks: map[int]int64{},
+		stopCh:   make(chan struct{}),
+	}
+	s.waitGroup.Add(1)
+	go func() {
+		defer s.waitGroup.Done()
+		ticker := time.NewTicker(time.Duration(settings.Interval) * time.Millisecond)
+		defer ticker.Stop()
+		s.sample()
+		for {
+			select {
+			case <-ticker.C:
+				s.sample()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// sample takes a snapshot of the process resources
+func (s *processSampler) sample() {
+	pids := []int{s.pid}
+	if s.settings.IncludeDescendants {
+		pids = getDescendantPids(s.pid)
+	}
+	var current processSample
+	found := false
+	for _, pid := range pids {
+		rss, threads, ticks, ok := readProcessSample(pid)
+		if !ok {
+			continue
+		}
+		found = true
+		current.rss += rss
+		current.threads += threads
+		// the cpu time is cumulative, we keep the last value of each process so the
+		// cpu used by descendants that already exited is not lost
+		if ticks > s.cpuTicks[pid] {
+			s.cpuTicks[pid] = ticks
+		}
+	}
+	if !found {
+		return
+	}
+	s.samples++
+	s.last = time.Now()
+	if current.rss > s.peakRSS {
+		s.peakRSS = current.rss
+	}
+	if current.threads > s.peakThreads {
+		s.peakThreads = current.threads
+	}
+	s.totalThreads += current.threads
+}
+
+// stop stops the sampling and waits for the sampling goroutine to finish
+func (s *processSampler) stop() {
+	if s == nil {
+		return
+	}
+	close(s.stopCh)
+	s.waitGroup.Wait()
+}
+
+// addMetrics adds the sampled values to a metrics map
+func (s *processSampler) addMetrics(metrics map[string]float64) {
+	if s == nil || s.samples == 0 {
+		return
+	}
+	metrics["process.sampled.samples"] = float64(s.samples)
+	metrics["process.sampled.peak_rss_bytes"] = float64(s.peakRSS)
+	metrics["process.sampled.max_threads"] = float64(s.peakThreads)
+	metrics["process.sampled.avg_threads"] = float64(s.totalThreads) / float64(s.samples)
+	if elapsed := s.last.Sub(s.start); elapsed > 0 {
+		var ticks int64
+		for _, value := range s.cpuTicks {
+			ticks += value
+		}
+		cpuTime := time.Duration(ticks) * time.Second / clockTicksPerSecond
+		metrics["process.sampled.avg_cpu_percent"] = float64(cpuTime) / float64(elapsed) * 100
+	}
+}
@@ -0,0 +1,96 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	samplingSupported = true
+	// USER_HZ is 100 on all the architectures supported by Go
+	clockTicksPerSecond = 100
+)
+
+// readProcessSample reads the resident set size, the number of threads and the cpu time (in clock ticks)
+// of a process from /proc
+func readProcessSample(pid int) (rss int64, threads int64, cpuTicks int64, ok bool) {
+	procPath := "/proc/" + strconv.Itoa(pid)
+	status, err := os.ReadFile(procPath + "/status")
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "VmRSS:") {
+			fields := strings.Fields(line[len("VmRSS:"):])
+			if len(fields) > 0 {
+				if value, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+					rss = value * 1024
+				}
+			}
+		} else if strings.HasPrefix(line, "Threads:") {
+			if value, err := strconv.ParseInt(strings.TrimSpace(line[len("Threads:"):]), 10, 64); err == nil {
+				threads = value
+			}
+		}
+	}
+
+	fields, _ := readProcStat(pid)
+	// utime and stime are the fields 14 and 15 of /proc/<pid>/stat (11 and 12 after the command name)
+	if len(fields) > 12 {
+		utime, _ := strconv.ParseInt(fields[11], 10, 64)
+		stime, _ := strconv.ParseInt(fields[12], 10, 64)
+		cpuTicks = utime + stime
+	}
+	return rss, threads, cpuTicks, true
+}
+
+// readProcStat returns the fields of /proc/<pid>/stat after the command name (starting with the state)
+func readProcStat(pid int) ([]string, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return nil, err
+	}
+	// the command name is between parentheses and can contain spaces
+	idx := bytes.LastIndexByte(data, ')')
+	if idx < 0 || idx+2 > len(data) {
+		return nil, nil
+	}
+	return strings.Fields(string(data[idx+2:])), nil
+}
+
+// getDescendantPids returns the pid and the pids of all its descendants
+func getDescendantPids(pid int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return []int{pid}
+	}
+	children := map[int][]int{}
+	for _, entry := range entries {
+		childPid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fields, err := readProcStat(childPid)
+		// the parent pid is the field 4 of /proc/<pid>/stat (1 after the command name)
+		if err != nil || len(fields) < 2 {
+			continue
+		}
+		if parentPid, err := strconv.Atoi(fields[1]); err == nil {
+			children[parentPid] = append(children[parentPid], childPid)
+		}
+	}
+
+	pids := []int{pid}
+	for i := 0; i < len(pids); i++ {
+		pids = append(pids, children[pids[i]]...)
+	}
+	return pids
+}
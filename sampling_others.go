@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+const (
+	samplingSupported   = false
+	clockTicksPerSecond = 100
+)
+
+// readProcessSample is only supported on Linux
+func readProcessSample(pid int) (rss int64, threads int64, cpuTicks int64, ok bool) {
+	return 0, 0, 0, false
+}
+
+// getDescendantPids is only supported on Linux
+func getDescendantPids(pid int) []int {
+	return []int{pid}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+const maxCPUAffinity = 1024
+
+// schedulingSettings contains the cpu affinity and niceness applied to the measured processes
+type schedulingSettings struct {
+	CPUAffinity []int `json:"cpuAffinity,omitempty"`
+	Niceness    *int  `json:"niceness,omitempty"`
+}
+
+func (p *processData) validateScheduling() error {
+	for _, cpu := range p.CPUAffinity {
+		if cpu < 0 || cpu >= maxCPUAffinity {
+			return fmt.Errorf("invalid cpu '%d' in cpuAffinity", cpu)
+		}
+	}
+	if p.Niceness != nil && (*p.Niceness < -20 || *p.Niceness > 19) {
+		return errors.New("niceness must be between -20 and 19")
+	}
+	return nil
+}
+
+// getSchedulingSettings returns the scheduling settings applied to the scenario processes
+func getSchedulingSettings(sce *scenario) *schedulingSettings {
+	if !schedulingSupported || (len(sce.CPUAffinity) == 0 && sce.Niceness == nil) {
+		return nil
+	}
+	return &schedulingSettings{
+		CPUAffinity: sce.CPUAffinity,
+		Niceness:    sce.Niceness,
+	}
+}
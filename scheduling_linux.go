@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const schedulingSupported = true
+
+// startProcess starts the command applying the cpu affinity and niceness of the scenario.
+// Both are per thread attributes on Linux inherited by the forked child, so they are applied
+// to a dedicated thread which starts the process, this way the settings are in place before exec.
+// The perf counters are opened on the same thread, so they are inherited by the child too.
+func startProcess(cmd *exec.Cmd, sce *scenario, counterNames []string) (*perfCounters, error) {
+	if len(sce.CPUAffinity) == 0 && sce.Niceness == nil && len(counterNames) == 0 {
+		return nil, cmd.Start()
+	}
+
+	type startResult struct {
+		counters *perfCounters
+		err      error
+	}
+	resultChan := make(chan startResult, 1)
+	go func() {
+		// the thread is never unlocked, so it's terminated when the goroutine exits
+		// and the scheduling settings don't leak to other goroutines.
+		runtime.LockOSThread()
+
+		if len(sce.CPUAffinity) > 0 {
+			if err := setThreadAffinity(sce.CPUAffinity); err != nil {
+				resultChan <- startResult{err: fmt.Errorf("error setting the cpu affinity: %v", err)}
+				return
+			}
+		}
+		if sce.Niceness != nil {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, *sce.Niceness); err != nil {
+				resultChan <- startResult{err: fmt.Errorf("error setting the niceness: %v", err)}
+				return
+			}
+		}
+		counters := openPerfCounters(counterNames)
+		if err := cmd.Start(); err != nil {
+			counters.close()
+			resultChan <- startResult{err: err}
+			return
+		}
+		resultChan <- startResult{counters: counters}
+	}()
+	result := <-resultChan
+	return result.counters, result.err
+}
+
+// setThreadAffinity sets the cpu affinity of the current thread
+func setThreadAffinity(cpus []int) error {
+	var mask [maxCPUAffinity / 64]uint64
+	for _, cpu := range cpus {
+		mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "os/exec"
+
+const schedulingSupported = false
+
+// startProcess starts the command, the cpu affinity, niceness and perf counters are only supported on Linux
+func startProcess(cmd *exec.Cmd, sce *scenario, counterNames []string) (*perfCounters, error) {
+	return nil, cmd.Start()
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/loov/hrtime"
+)
+
+const (
+	defaultServiceReadyTimeout    = 60
+	defaultServiceShutdownTimeout = 5
+	serviceReadyPollInterval      = 50 * time.Millisecond
+
+	// serviceOutputMaxSize is the tail of the service output kept for the error messages
+	serviceOutputMaxSize = 64 * 1024
+)
+
+type (
+	// service is a long-lived process started once per scenario, the iterations time the probes against it
+	service struct {
+		ProcessName      *string           `json:"processName"`
+		ProcessArguments *arguments        `json:"processArguments"`
+		Shell            *bool             `json:"shell"`
+		Ready            serviceReadyCheck `json:"ready"`
+		ShutdownTimeout  int               `json:"shutdownTimeout"`
+	}
+
+	// serviceReadyCheck configures how to detect that the service is ready
+	serviceReadyCheck struct {
+		TCP       string `json:"tcp"`
+		HTTP      string `json:"http"`
+		Pattern   string `json:"pattern"`
+		Timeout   int    `json:"timeout"`
+		readiness *readiness
+	}
+
+	// httpProbe is a built-in http request used as the measured operation of an iteration
+	httpProbe struct {
+		URL              string            `json:"url"`
+		Method           string            `json:"method"`
+		Headers          map[string]string `json:"headers"`
+		Body             string            `json:"body"`
+		ExpectedStatus   int               `json:"expectedStatus"`
+		DisableKeepAlive bool              `json:"disableKeepAlive"`
+		client           *http.Client
+	}
+
+	// runningService is a started service
+	runningService struct {
+		cmd             *exec.Cmd
+		output          syncBuffer
+		exited          chan struct{}
+		shutdownTimeout time.Duration
+	}
+)
+
+func (s *service) validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.ProcessName == nil || *s.ProcessName == "" {
+		return errors.New("service processName is required")
+	}
+	if s.Ready.TCP == "" && s.Ready.HTTP == "" && s.Ready.Pattern == "" {
+		return errors.New("service ready requires a tcp address, an http url or a pattern")
+	}
+	if s.Ready.Pattern != "" {
+		s.Ready.readiness = &readiness{Pattern: s.Ready.Pattern}
+		if err := s.Ready.readiness.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *httpProbe) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.URL == "" {
+		return errors.New("httpProbe url is required")
+	}
+	if p.Method == "" {
+		p.Method = http.MethodGet
+	}
+	if p.ExpectedStatus == 0 {
+		p.ExpectedStatus = http.StatusOK
+	}
+	return nil
+}
+
+// startService starts the scenario service and waits until it's ready, returning the time it took
+func startService(sce *scenario) (*runningService, time.Duration, error) {
+	svc := sce.Service
+	var workingDirectory string
+	if sce.WorkingDirectory != nil {
+		workingDirectory = *sce.WorkingDirectory
+	}
+
+	running := &runningService{
+		output:          syncBuffer{maxSize: serviceOutputMaxSize},
+		exited:          make(chan struct{}),
+		shutdownTimeout: time.Duration(defaultServiceShutdownTimeout) * time.Second,
+	}
+	if svc.ShutdownTimeout > 0 {
+		running.shutdownTimeout = time.Duration(svc.ShutdownTimeout) * time.Second
+	}
+
+	cmd := newCommand(context.Background(), *svc.ProcessName, svc.ProcessArguments, svc.Shell != nil && *svc.Shell)
+	cmd.Dir = workingDirectory
+	cmd.Env = buildEnvironment(&sce.processData)
+	setProcessGroup(cmd)
+	var watcher *outputWatcher
+	if svc.Ready.readiness != nil {
+		watcher = newOutputWatcher(svc.Ready.readiness, nil)
+	}
+	cmd.Stdout = watcher.wrap(readinessStreamStdout, &running.output)
+	cmd.Stderr = watcher.wrap(readinessStreamStderr, &running.output)
+	running.cmd = cmd
+
+	start := time.Now()
+	if _, err := startProcess(cmd, sce, nil); err != nil {
+		return nil, 0, err
+	}
+	trackProcess(cmd)
+	go func() {
+		_ = cmd.Wait()
+		untrackProcess(cmd)
+		close(running.exited)
+	}()
+
+	readyTimeout := time.Duration(defaultServiceReadyTimeout) * time.Second
+	if svc.Ready.Timeout > 0 {
+		readyTimeout = time.Duration(svc.Ready.Timeout) * time.Second
+	}
+	readyDeadline := time.After(readyTimeout)
+	for {
+		if svc.Ready.isReady(watcher) {
+			return running, time.Since(start), nil
+		}
+		select {
+		case <-running.exited:
+			return nil, 0, errors.New(fmt.Sprintf("the service exited before being ready:\n%s", running.output.String()))
+		case <-readyDeadline:
+			running.stop()
+			return nil, 0, errors.New(fmt.Sprintf("the service was not ready after %v:\n%s", readyTimeout, running.output.String()))
+		case <-time.After(serviceReadyPollInterval):
+		}
+	}
+}
+
+// isReady returns true if all the configured checks succeed
+func (c *serviceReadyCheck) isReady(watcher *outputWatcher) bool {
+	if watcher != nil && !watcher.matched() {
+		return false
+	}
+	if c.TCP != "" {
+		conn, err := net.DialTimeout("tcp", c.TCP, time.Second)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+	}
+	if c.HTTP != "" {
+		client := http.Client{Timeout: time.Second}
+		resp, err := client.Get(c.HTTP)
+		if err != nil {
+			return false
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// stop terminates the service process group, killing it if it doesn't exit in time
+func (s *runningService) stop() {
+	_ = terminateProcessGroup(s.cmd)
+	select {
+	case <-s.exited:
+		return
+	case <-time.After(s.shutdownTimeout):
+	}
+	_ = killProcessGroup(s.cmd)
+	<-s.exited
+}
+
+// getClient returns the http client used by the probe
+func (p *httpProbe) getClient(timeout time.Duration) *http.Client {
+	if p.client == nil {
+		p.client = &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				DisableKeepAlives: p.DisableKeepAlive,
+			},
+		}
+	}
+	return p.client
+}
+
+// runHTTPProbe times an http request of the scenario http probe
+func runHTTPProbe(sce *scenario) scenarioDataPoint {
+	probe := sce.HTTPProbe
+	client := probe.getClient(time.Duration(sce.Timeout.MaxDuration) * time.Second)
+
+	var bodyReader io.Reader
+	if probe.Body != "" {
+		bodyReader = strings.NewReader(probe.Body)
+	}
+	req, err := http.NewRequest(probe.Method, probe.URL, bodyReader)
+	if err != nil {
+		now := time.Now()
+		return scenarioDataPoint{
+			Start:          now,
+			End:            now,
+			Status:         dataPointStatusError,
+			Error:          errors.New(fmt.Sprintf("\nError creating the http request: %s", err.Error())),
+			shouldContinue: false,
+		}
+	}
+	for k, v := range probe.Headers {
+		req.Header.Set(k, v)
+	}
+
+	var body bytes.Buffer
+	start := time.Now()
+	startDur := hrtime.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		_, err = io.Copy(&body, resp.Body)
+		_ = resp.Body.Close()
+	}
+	endDur := hrtime.Now()
+	end := time.Now()
+
+	status := dataPointStatusSuccess
+	var netErr net.Error
+	if err != nil && errors.As(err, &netErr) && netErr.Timeout() {
+		status = dataPointStatusTimeout
+		err = errors.New(fmt.Sprintf("\nhttp request timed out: %s", err.Error()))
+	} else if err != nil {
+		status = dataPointStatusError
+		err = errors.New(fmt.Sprintf("\nhttp request failed: %s", err.Error()))
+	} else if resp.StatusCode != probe.ExpectedStatus {
+		status = dataPointStatusFailed
+		err = errors.New(fmt.Sprintf("\n%sunexpected http status code %d (expected: %d)", body.String(), resp.StatusCode, probe.ExpectedStatus))
+	} else if aErr := checkAssertions(sce, 0, false, body.Bytes(), nil); aErr != nil {
+		// the response body is validated with the stdout assertions
+		status = dataPointStatusFailed
+		err = errors.New(fmt.Sprintf("\n%s%s", body.String(), aErr.Error()))
+	}
+
+	return scenarioDataPoint{
+		Start:          start,
+		End:            end,
+		Duration:       endDur - startDur,
+		Status:         status,
+		Error:          err,
+		metrics:        map[string]float64{},
+		shouldContinue: true,
+		stdout:         body.Bytes(),
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+// newShellCommand creates a command executing the command line through the POSIX shell
+func newShellCommand(ctx context.Context, name string, args *arguments) *exec.Cmd {
+	return exec.CommandContext(ctx, "/bin/sh", "-c", shellCommandLine(name, args, quoteShellWord))
+}
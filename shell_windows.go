@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// newShellCommand creates a command executing the command line through cmd.exe, the command line is
+// passed verbatim because cmd.exe doesn't follow the quoting rules used to build the process arguments
+func newShellCommand(ctx context.Context, name string, args *arguments) *exec.Cmd {
+	cmdLine := shellCommandLine(name, args, quoteWindowsWord)
+	cmd := exec.CommandContext(ctx, "cmd")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: fmt.Sprintf(`cmd /S /C "%s"`, cmdLine)}
+	return cmd
+}
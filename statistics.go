@@ -0,0 +1,165 @@
+package main
+
+import (
+	"math"
+
+	"github.com/montanaflynn/stats"
+)
+
+// relativeStdErr returns the standard error of the mean relative to the mean
+func relativeStdErr(values []float64) float64 {
+	if len(values) < 2 {
+		return math.Inf(1)
+	}
+	mean, _ := stats.Mean(values)
+	stdev, _ := stats.StandardDeviationSample(values)
+	if mean == 0 {
+		return math.Inf(1)
+	}
+	return stdev / math.Sqrt(float64(len(values))) / math.Abs(mean)
+}
+
+// relativeConfidenceHalfWidth returns the half width of the confidence interval of the mean
+// (using the Student's t distribution) relative to the mean
+func relativeConfidenceHalfWidth(values []float64, confidenceLevel float64) float64 {
+	if len(values) < 2 {
+		return math.Inf(1)
+	}
+	tValue := studentTQuantile(1-(1-confidenceLevel)/2, float64(len(values)-1))
+	return tValue * relativeStdErr(values)
+}
+
+// relativeDrift returns the change over the values predicted by their least squares line, relative to their mean
+func relativeDrift(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return math.Inf(1)
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	mean := sumY / n
+	if mean == 0 {
+		return math.Inf(1)
+	}
+	slope := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	return math.Abs(slope*(n-1)) / math.Abs(mean)
+}
+
+// studentTCDF returns the cumulative distribution function of the Student's t distribution
+func studentTCDF(t float64, df float64) float64 {
+	if math.IsInf(t, 1) {
+		return 1
+	}
+	if math.IsInf(t, -1) {
+		return 0
+	}
+	x := df / (df + t*t)
+	tail := 0.5 * regularizedIncompleteBeta(df/2, 0.5, x)
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// studentTQuantile returns the inverse of the cumulative distribution function of the Student's t distribution
+func studentTQuantile(p float64, df float64) float64 {
+	if p <= 0 {
+		return math.Inf(-1)
+	}
+	if p >= 1 {
+		return math.Inf(1)
+	}
+	if p == 0.5 {
+		return 0
+	}
+
+	// Bisection over the CDF, the t quantiles we use are always within this range
+	low, high := -1e3, 1e3
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		if studentTCDF(mid, df) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+		if high-low < 1e-12 {
+			break
+		}
+	}
+	return (low + high) / 2
+}
+
+// regularizedIncompleteBeta returns the regularized incomplete beta function I_x(a, b)
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// use the continued fraction directly where it converges quickly, otherwise use the symmetry relation
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction for the incomplete beta function (modified Lentz's method)
+func betaContinuedFraction(a, b, x float64) float64 {
+	const maxIterations = 300
+	const epsilon = 1e-15
+	const tiny = 1e-300
+
+	qab := a + b
+	qap := a + 1
+	qam := a - 1
+	c := 1.0
+	d := 1 - qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
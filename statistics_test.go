@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStudentTCDF(t *testing.T) {
+	tests := []struct {
+		t        float64
+		df       float64
+		expected float64
+	}{
+		{t: 0, df: 5, expected: 0.5},
+		{t: 1, df: 3, expected: 0.804499},
+		{t: 6.313752, df: 1, expected: 0.95},
+		{t: 4.032143, df: 5, expected: 0.995},
+		{t: 2.228139, df: 10, expected: 0.975},
+		{t: 2.042272, df: 30, expected: 0.975},
+		{t: -2.228139, df: 10, expected: 0.025},
+		{t: math.Inf(1), df: 10, expected: 1},
+		{t: math.Inf(-1), df: 10, expected: 0},
+	}
+	for _, test := range tests {
+		if actual := studentTCDF(test.t, test.df); math.Abs(actual-test.expected) > 1e-6 {
+			t.Errorf("studentTCDF(%v, %v) = %v, expected %v", test.t, test.df, actual, test.expected)
+		}
+	}
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct {
+		p        float64
+		df       float64
+		expected float64
+	}{
+		{p: 0.5, df: 5, expected: 0},
+		{p: 0.95, df: 1, expected: 6.313752},
+		{p: 0.995, df: 5, expected: 4.032143},
+		{p: 0.975, df: 10, expected: 2.228139},
+		{p: 0.975, df: 30, expected: 2.042272},
+		{p: 0.025, df: 10, expected: -2.228139},
+	}
+	for _, test := range tests {
+		if actual := studentTQuantile(test.p, test.df); math.Abs(actual-test.expected) > 1e-5 {
+			t.Errorf("studentTQuantile(%v, %v) = %v, expected %v", test.p, test.df, actual, test.expected)
+		}
+	}
+}
+
+func TestRelativeStdErr(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		expected float64
+	}{
+		{name: "constant", values: []float64{10, 10, 10}, expected: 0},
+		// sample stdev 1.581139, n 5, mean 3
+		{name: "sequence", values: []float64{1, 2, 3, 4, 5}, expected: 1.581139 / math.Sqrt(5) / 3},
+		{name: "single value", values: []float64{10}, expected: math.Inf(1)},
+		{name: "zero mean", values: []float64{-1, 1}, expected: math.Inf(1)},
+	}
+	for _, test := range tests {
+		actual := relativeStdErr(test.values)
+		if !(actual == test.expected || math.Abs(actual-test.expected) < 1e-6) {
+			t.Errorf("%s: relativeStdErr = %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestRelativeDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		expected float64
+	}{
+		{name: "flat", values: []float64{100, 100, 100, 100}, expected: 0},
+		// slope 1 over 4 iterations predicts a change of 3 for a mean of 101.5
+		{name: "increasing", values: []float64{100, 101, 102, 103}, expected: 3 / 101.5},
+		{name: "decreasing", values: []float64{103, 102, 101, 100}, expected: 3 / 101.5},
+		// noise without a trend
+		{name: "alternating", values: []float64{100, 102, 100, 102, 100, 102}, expected: 6.0 / 7 / 101},
+		{name: "single value", values: []float64{100}, expected: math.Inf(1)},
+	}
+	for _, test := range tests {
+		actual := relativeDrift(test.values)
+		if !(actual == test.expected || math.Abs(actual-test.expected) < 1e-6) {
+			t.Errorf("%s: relativeDrift = %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (p *processData) validateStdin() error {
+	if p.Stdin != nil && p.StdinFile != nil {
+		return errors.New("stdin and stdinFile can't be used at the same time")
+	}
+	return nil
+}
+
+// openStdin returns a new reader for the stdin of a scenario process, the returned
+// function must be called to release the reader once the process has finished.
+func openStdin(sce *scenario, workingDirectory string) (io.Reader, func(), error) {
+	if sce.StdinFile != nil && *sce.StdinFile != "" {
+		stdinFile := *sce.StdinFile
+		if !filepath.IsAbs(stdinFile) {
+			stdinFile = filepath.Join(workingDirectory, stdinFile)
+		}
+		file, err := os.Open(stdinFile)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return file, func() { _ = file.Close() }, nil
+	}
+
+	if sce.Stdin != nil {
+		return strings.NewReader(*sce.Stdin), func() {}, nil
+	}
+
+	return nil, func() {}, nil
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"math"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 )
 
 func replaceCustomVars(value string) string {
@@ -49,3 +51,27 @@ func orderByKey(data map[string][]float64) []metricsItem {
 	})
 	return value
 }
+
+// syncBuffer is a bytes.Buffer safe to be written from multiple goroutines,
+// if maxSize is set only the last maxSize bytes are kept
+type syncBuffer struct {
+	mutex   sync.Mutex
+	buffer  bytes.Buffer
+	maxSize int
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	n, err := b.buffer.Write(p)
+	if b.maxSize > 0 && b.buffer.Len() > b.maxSize {
+		b.buffer.Next(b.buffer.Len() - b.maxSize)
+	}
+	return n, err
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}